@@ -5,7 +5,6 @@ go 1.27.0
 require (
 	github.com/google/go-cmp v0.7.0
 	github.com/pelletier/go-toml/v2 v2.4.3
+	golang.org/x/sys v0.47.0
 	golang.org/x/term v0.45.0
 )
-
-require golang.org/x/sys v0.47.0 // indirect
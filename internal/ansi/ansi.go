@@ -0,0 +1,188 @@
+// Package ansi turns terminal output into plain text.
+package ansi
+
+import (
+	"bytes"
+	"unicode/utf8"
+)
+
+const (
+	esc = 0x1b
+	bel = 0x07
+)
+
+// Normalize returns line as a terminal would show it. Escape sequences such
+// as colors are removed, and text overwritten after a carriage return or a
+// backspace is replaced, so that a progress bar redrawn many times on one
+// line results in its final state.
+//
+// line must not contain a newline.
+func Normalize(line []byte) []byte {
+	// Most lines have nothing to render
+	if !bytes.ContainsFunc(line, isControl) {
+		return line
+	}
+
+	var s screen
+	for i := 0; i < len(line); {
+		b := line[i]
+		switch {
+		case b == esc:
+			i = s.escape(line, i+1)
+			continue
+		case b == '\r':
+			s.col = 0
+		case b == '\b':
+			s.col = max(s.col-1, 0)
+		case b == '\t':
+			s.put('\t')
+		case b < 0x20 || b == 0x7f:
+			// Other control characters are not visible
+		default:
+			r, size := utf8.DecodeRune(line[i:])
+			if r == utf8.RuneError && size == 1 {
+				// Keep invalid bytes as they are
+				s.putByte(b)
+				i++
+				continue
+			}
+			s.put(r)
+			i += size
+			continue
+		}
+		i++
+	}
+
+	return s.bytes()
+}
+
+func isControl(r rune) bool {
+	return r < 0x20 && r != '\t' || r == 0x7f
+}
+
+// screen is a single terminal line with a cursor.
+type screen struct {
+	cells [][]byte
+	col   int
+}
+
+func (s *screen) put(r rune) {
+	s.set(utf8.AppendRune(nil, r))
+}
+
+func (s *screen) putByte(b byte) {
+	s.set([]byte{b})
+}
+
+func (s *screen) set(cell []byte) {
+	for len(s.cells) < s.col {
+		s.cells = append(s.cells, []byte{' '})
+	}
+	if s.col < len(s.cells) {
+		s.cells[s.col] = cell
+	} else {
+		s.cells = append(s.cells, cell)
+	}
+	s.col++
+}
+
+// escape handles the escape sequence starting at line[i], just after ESC,
+// and returns the index of the byte following it.
+func (s *screen) escape(line []byte, i int) int {
+	if i >= len(line) {
+		return i
+	}
+
+	switch line[i] {
+	case '[':
+		return s.csi(line, i+1)
+	case ']', 'P', '_', '^', 'X':
+		// OSC, DCS, APC, PM and SOS strings end with BEL or ST (ESC \)
+		for i++; i < len(line); i++ {
+			if line[i] == bel {
+				return i + 1
+			}
+			if line[i] == esc && i+1 < len(line) && line[i+1] == '\\' {
+				return i + 2
+			}
+		}
+		return i
+	}
+
+	// Other sequences are optional intermediate bytes and a final byte,
+	// like ESC ( B
+	for i < len(line) && 0x20 <= line[i] && line[i] <= 0x2f {
+		i++
+	}
+	return min(i+1, len(line))
+}
+
+// csi handles a Control Sequence Introducer sequence, of which the
+// parameters start at line[i]. Only the sequences that move the cursor
+// within the line or erase it affect the result. The others, including
+// SGR sequences which set colors, are removed.
+func (s *screen) csi(line []byte, i int) int {
+	start := i
+	for i < len(line) && 0x20 <= line[i] && line[i] <= 0x3f {
+		i++
+	}
+	if i >= len(line) {
+		return i
+	}
+	params := line[start:i]
+	final := line[i]
+
+	// The cursor never needs to move past the end of what is printed
+	limit := len(line)
+
+	switch final {
+	case 'K':
+		switch firstParam(params, 0) {
+		case 0:
+			s.cells = s.cells[:min(s.col, len(s.cells))]
+		case 1:
+			for j := 0; j <= s.col && j < len(s.cells); j++ {
+				s.cells[j] = []byte{' '}
+			}
+		case 2:
+			s.cells = nil
+		}
+	case 'G':
+		s.col = min(max(firstParam(params, 1)-1, 0), limit)
+	case 'C':
+		s.col = min(s.col+max(firstParam(params, 1), 1), limit)
+	case 'D':
+		s.col = max(s.col-max(firstParam(params, 1), 1), 0)
+	}
+
+	return i + 1
+}
+
+// firstParam returns the first numeric parameter of a CSI sequence, or def
+// if it is omitted.
+func firstParam(params []byte, def int) int {
+	n := 0
+	digits := false
+	for _, b := range params {
+		if b < '0' || '9' < b {
+			break
+		}
+		n = n*10 + int(b-'0')
+		digits = true
+		if n > 1<<20 {
+			break
+		}
+	}
+	if !digits {
+		return def
+	}
+	return n
+}
+
+func (s *screen) bytes() []byte {
+	line := make([]byte, 0, len(s.cells))
+	for _, cell := range s.cells {
+		line = append(line, cell...)
+	}
+	return line
+}
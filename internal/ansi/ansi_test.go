@@ -0,0 +1,85 @@
+package ansi_test
+
+import (
+	"testing"
+
+	. "github.com/catatsuy/notify_slack/internal/ansi"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		line string
+		want string
+	}{
+		{
+			name: "plain",
+			line: "hello\tworld",
+			want: "hello\tworld",
+		},
+		{
+			name: "colors",
+			line: "\x1b[1;32mPASS\x1b[0m ok \x1b[31mFAIL\x1b[m",
+			want: "PASS ok FAIL",
+		},
+		{
+			name: "progress bar",
+			line: "  10%\r  50%\r 100%",
+			want: " 100%",
+		},
+		{
+			name: "shorter text overwrites the beginning",
+			line: "Downloading\rDone",
+			want: "Doneloading",
+		},
+		{
+			name: "erase line",
+			line: "Downloading\r\x1b[KDone",
+			want: "Done",
+		},
+		{
+			name: "erase whole line",
+			line: "Downloading\x1b[2K\rDone",
+			want: "Done",
+		},
+		{
+			name: "backspace",
+			line: "spinner |\b/\b-\b\\\b|",
+			want: "spinner |",
+		},
+		{
+			name: "cursor movement",
+			line: "abc\x1b[1Gx\x1b[1Cz\x1b[2Dy",
+			want: "xyz",
+		},
+		{
+			name: "hyperlink",
+			line: "\x1b]8;;https://example.com\x07link\x1b]8;;\x1b\\",
+			want: "link",
+		},
+		{
+			name: "charset and bell",
+			line: "\x1b(Babc\x07",
+			want: "abc",
+		},
+		{
+			name: "multi-byte characters",
+			line: "あいう\r\x1b[33mえ\x1b[0m",
+			want: "えいう",
+		},
+		{
+			name: "truncated sequence",
+			line: "abc\x1b[3",
+			want: "abc",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := string(Normalize([]byte(tt.line)))
+			if got != tt.want {
+				t.Errorf("Normalize(%q) = %q; want %q", tt.line, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/catatsuy/notify_slack/internal/config"
+	"github.com/catatsuy/notify_slack/internal/throttle"
+)
+
+const (
+	// defaultAlertCooldown is the minimum interval between two mentions by
+	// the same rule unless configured otherwise.
+	defaultAlertCooldown = 5 * time.Minute
+
+	defaultAlertEmoji = ":rotating_light:"
+
+	silenceEmoji  = ":hourglass:"
+	recoveryEmoji = ":white_check_mark:"
+)
+
+type alertRule struct {
+	pattern *regexp.Regexp
+	mention string
+	emoji   string
+	color   string
+
+	// lastMention is when the rule last mentioned someone
+	lastMention time.Time
+}
+
+// alerter highlights the lines of a flush matching the alert rules and
+// decides whom to mention. A rule mentions at most once per cooldown, so
+// that a flood of errors doesn't keep notifying people.
+type alerter struct {
+	rules    []*alertRule
+	cooldown time.Duration
+	now      func() time.Time
+	// plain leaves the matching lines as is instead of prefixing them with
+	// the emoji, which would show literally in a code block
+	plain bool
+}
+
+func newAlerter(rules []config.AlertRule, cooldown time.Duration) (*alerter, error) {
+	if cooldown <= 0 {
+		cooldown = defaultAlertCooldown
+	}
+
+	a := &alerter{cooldown: cooldown, now: time.Now}
+	for _, r := range rules {
+		re, err := regexp.Compile(r.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect value to alert pattern option: %s: %w", r.Pattern, err)
+		}
+		emoji := r.Emoji
+		if emoji == "" {
+			emoji = defaultAlertEmoji
+		}
+		a.rules = append(a.rules, &alertRule{
+			pattern: re,
+			mention: r.Mention,
+			emoji:   emoji,
+			color:   r.Color,
+		})
+	}
+
+	return a, nil
+}
+
+// alert is the result of applying the rules to a flush.
+type alert struct {
+	// text is the output with the matching lines highlighted
+	text string
+	// mention is the mentions to prepend, if any
+	mention string
+	// color is the color of the first matching rule which has one
+	color string
+}
+
+// message returns the text with the mention on its own line before it.
+func (a alert) message() string {
+	if a.mention == "" {
+		return a.text
+	}
+	if a.text == "" {
+		return a.mention
+	}
+	return a.mention + "\n" + a.text
+}
+
+// apply highlights the lines of output matching a rule. A nil alerter
+// returns output as is.
+func (a *alerter) apply(output string) alert {
+	res := alert{text: output}
+	if a == nil {
+		return res
+	}
+
+	var b strings.Builder
+	var mentions []string
+	matched := false
+	now := a.now()
+
+	for line := range strings.Lines(output) {
+		// Match without the newline so that $ matches at the end of the line
+		rule := a.match(strings.TrimSuffix(line, "\n"))
+		if rule == nil {
+			b.WriteString(line)
+			continue
+		}
+		matched = true
+
+		if !a.plain {
+			b.WriteString(rule.emoji)
+			b.WriteString(" ")
+		}
+		b.WriteString(line)
+
+		if res.color == "" {
+			res.color = rule.color
+		}
+		if rule.mention != "" && now.Sub(rule.lastMention) >= a.cooldown {
+			rule.lastMention = now
+			if !slices.Contains(mentions, rule.mention) {
+				mentions = append(mentions, rule.mention)
+			}
+		}
+	}
+
+	if matched {
+		res.text = b.String()
+		res.mention = strings.Join(mentions, " ")
+	}
+
+	return res
+}
+
+// match returns the first rule matching line.
+func (a *alerter) match(line string) *alertRule {
+	for _, r := range a.rules {
+		if r.pattern.MatchString(line) {
+			return r
+		}
+	}
+	return nil
+}
+
+// silenceMessage returns the text posted when the input has been silent for
+// too long, or when it resumes.
+func silenceMessage(ev throttle.SilenceEvent) string {
+	elapsed := ev.Elapsed.Round(time.Second)
+
+	if ev.Resumed {
+		return fmt.Sprintf("%s Output resumed after %s of silence", recoveryEmoji, elapsed)
+	}
+	if ev.LastLine == "" {
+		return fmt.Sprintf("%s No output for %s since the start", silenceEmoji, elapsed)
+	}
+	return fmt.Sprintf("%s No output for %s. The last line was:\n>%s", silenceEmoji, elapsed, ev.LastLine)
+}
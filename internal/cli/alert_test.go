@@ -0,0 +1,179 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	"github.com/catatsuy/notify_slack/internal/config"
+	"github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/catatsuy/notify_slack/internal/throttle"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAlerter_apply(t *testing.T) {
+	a, err := newAlerter([]config.AlertRule{
+		{Pattern: "panic", Mention: "<!here>", Emoji: ":fire:", Color: slack.ColorDanger},
+		// Anchors match at the ends of each line
+		{Pattern: "^ERROR|FAILED$", Mention: "<@U123>"},
+	}, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	a.now = func() time.Time { return now }
+
+	got := a.apply("ok\nERROR one\npanic: boom\nbuild FAILED\nFAILED to match\n")
+	expected := alert{
+		text:    "ok\n:rotating_light: ERROR one\n:fire: panic: boom\n:rotating_light: build FAILED\nFAILED to match\n",
+		mention: "<@U123> <!here>",
+		color:   slack.ColorDanger,
+	}
+	if diff := cmp.Diff(expected, got, cmp.AllowUnexported(alert{})); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+
+	// Within the cooldown the lines are still highlighted
+	now = now.Add(30 * time.Second)
+	got = a.apply("FAILED\n")
+	expected = alert{text: ":rotating_light: FAILED\n"}
+	if diff := cmp.Diff(expected, got, cmp.AllowUnexported(alert{})); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+
+	now = now.Add(30 * time.Second)
+	got = a.apply("FAILED\n")
+	if got.mention != "<@U123>" {
+		t.Errorf("mention = %q; want %q", got.mention, "<@U123>")
+	}
+
+	got = a.apply("nothing\n")
+	expected = alert{text: "nothing\n"}
+	if diff := cmp.Diff(expected, got, cmp.AllowUnexported(alert{})); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+}
+
+func TestNewAlerter_invalidPattern(t *testing.T) {
+	_, err := newAlerter([]config.AlertRule{{Pattern: "("}}, 0)
+	if err == nil || !strings.Contains(err.Error(), "alert") {
+		t.Errorf("error = %v; want it to contain %q", err, "alert")
+	}
+}
+
+func TestStreamToSlack_alert(t *testing.T) {
+	var params []slack.PostTextParam
+	cl := &CLI{
+		outStream:   new(bytes.Buffer),
+		inputStream: strings.NewReader("building\nFAILED: test\n"),
+		sClient: &fakeSlackClient{
+			FakePostText: func(ctx context.Context, param *slack.PostTextParam) error {
+				params = append(params, *param)
+				return nil
+			},
+		},
+		conf: config.NewConfig(),
+	}
+	cl.conf.Duration = time.Hour
+
+	status := cl.streamToSlack(t.Context(), &cliOptions{alertPattern: "FAILED", alertMention: "<!here>"})
+	if status != ExitCodeOK {
+		t.Errorf("ExitStatus=%d, want %d", status, ExitCodeOK)
+	}
+
+	text := "building\n:rotating_light: FAILED: test\n"
+	expected := []slack.PostTextParam{
+		{
+			Text:        "<!here>",
+			Attachments: []slack.Attachment{{Color: slack.ColorDanger, Fallback: text, Text: text}},
+		},
+	}
+	if diff := cmp.Diff(expected, params); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+}
+
+func TestSilenceMessage(t *testing.T) {
+	tests := []struct {
+		input    throttle.SilenceEvent
+		expected string
+	}{
+		{
+			input:    throttle.SilenceEvent{LastLine: "copying chunk 12", Elapsed: 10*time.Minute + 300*time.Millisecond},
+			expected: ":hourglass: No output for 10m0s. The last line was:\n>copying chunk 12",
+		},
+		{
+			input:    throttle.SilenceEvent{Elapsed: 10 * time.Minute},
+			expected: ":hourglass: No output for 10m0s since the start",
+		},
+		{
+			input:    throttle.SilenceEvent{Resumed: true, LastLine: "done", Elapsed: 25 * time.Minute},
+			expected: ":white_check_mark: Output resumed after 25m0s of silence",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := silenceMessage(tt.input); got != tt.expected {
+			t.Errorf("got %q, want %q", got, tt.expected)
+		}
+	}
+}
+
+func TestRunStream_alertIfSilent(t *testing.T) {
+	for _, recovery := range []bool{false, true} {
+		synctest.Test(t, func(t *testing.T) {
+			var texts []string
+			cl := &CLI{
+				outStream: new(bytes.Buffer),
+				errStream: new(bytes.Buffer),
+				sClient: &fakeSlackClient{
+					FakePostText: func(ctx context.Context, param *slack.PostTextParam) error {
+						texts = append(texts, param.Text)
+						return nil
+					},
+				},
+				conf: config.NewConfig(),
+			}
+			cl.conf.Duration = time.Hour
+			cl.conf.FlushFirstLine = true
+			cl.conf.AlertIfSilent = 10 * time.Minute
+			cl.conf.AlertRecovery = recovery
+
+			opts := &cliOptions{}
+			settings, err := cl.parseStreamSettings(opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			pr, pw := io.Pipe()
+			done := make(chan error)
+			go func() {
+				done <- cl.runStream(t.Context(), opts, settings, pr, slack.PostTextParam{})
+			}()
+
+			io.WriteString(pw, "step 1\n")
+			time.Sleep(15 * time.Minute)
+			io.WriteString(pw, "step 2\n")
+			pw.Close()
+			if err := <-done; err != nil {
+				t.Fatal(err)
+			}
+
+			expected := []string{
+				"step 1\n",
+				":hourglass: No output for 10m0s. The last line was:\n>step 1",
+			}
+			if recovery {
+				expected = append(expected, ":white_check_mark: Output resumed after 15m0s of silence")
+			}
+			expected = append(expected, "step 2\n")
+			if diff := cmp.Diff(expected, texts); diff != "" {
+				t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
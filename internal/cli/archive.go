@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/catatsuy/notify_slack/internal/slack"
+)
+
+// archive spools all lines of a session to a temporary file, to be uploaded
+// as a whole when the input ends. Lines are written after the ANSI and
+// redact stages, but before they are filtered.
+type archive struct {
+	mu   sync.Mutex // Serializes writes from several inputs
+	file *os.File
+	gz   *gzip.Writer
+	w    io.Writer
+	// err is the first write error. Later lines are not written.
+	err error
+}
+
+func newArchive(compress bool) (*archive, error) {
+	f, err := os.CreateTemp("", "notify_slack-archive-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the archive: %w", err)
+	}
+
+	a := &archive{file: f, w: f}
+	if compress {
+		a.gz = gzip.NewWriter(f)
+		a.w = a.gz
+	}
+	return a, nil
+}
+
+// stage is a throttle.Stage that writes each line to the archive.
+func (a *archive) stage(line []byte, emit func([]byte)) {
+	a.mu.Lock()
+	if a.err == nil {
+		_, a.err = a.w.Write(line)
+	}
+	if a.err == nil {
+		_, a.err = io.WriteString(a.w, "\n")
+	}
+	a.mu.Unlock()
+
+	emit(line)
+}
+
+// content returns the archived data. No line must be written after.
+func (a *archive) content() ([]byte, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.err != nil {
+		return nil, fmt.Errorf("failed to write the archive: %w", a.err)
+	}
+	if a.gz != nil {
+		if err := a.gz.Close(); err != nil {
+			return nil, fmt.Errorf("failed to write the archive: %w", err)
+		}
+	}
+
+	size, err := a.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	if size > maxSnippetBytes {
+		return nil, fmt.Errorf("archive is %d bytes; uploads are capped at %d bytes", size, maxSnippetBytes)
+	}
+
+	content := make([]byte, size)
+	if _, err := a.file.ReadAt(content, 0); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read the archive: %w", err)
+	}
+	return content, nil
+}
+
+// remove deletes the temporary file.
+func (a *archive) remove() {
+	a.file.Close()
+	os.Remove(a.file.Name())
+}
+
+// archiveFilename returns a name like job-web1-20240501-100000.log.gz.
+func archiveFilename(host string, start time.Time, compress bool) string {
+	if host == "" {
+		host = "unknown"
+	}
+	name := fmt.Sprintf("job-%s-%s.log", host, start.Format("20060102-150405"))
+	if compress {
+		name += ".gz"
+	}
+	return name
+}
+
+// uploadArchive uploads the lines of the session as a file.
+func (c *CLI) uploadArchive(ctx context.Context, a *archive, host string, start time.Time) error {
+	content, err := a.content()
+	if err != nil {
+		return err
+	}
+
+	compress := a.gz != nil
+	param := &slack.PostFileParam{
+		ChannelID: c.conf.ChannelID,
+		Filename:  archiveFilename(host, start, compress),
+	}
+	if !compress {
+		param.SnippetType = "text"
+	}
+
+	if err := c.sClient.PostFile(ctx, param, content); err != nil {
+		return fmt.Errorf("failed to upload the archive: %w", err)
+	}
+	return nil
+}
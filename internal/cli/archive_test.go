@@ -0,0 +1,113 @@
+package cli
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/catatsuy/notify_slack/internal/config"
+	"github.com/catatsuy/notify_slack/internal/slack"
+)
+
+func TestArchiveFilename(t *testing.T) {
+	start := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	if got, want := archiveFilename("web1", start, false), "job-web1-20240501-100000.log"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+	if got, want := archiveFilename("", start, true), "job-unknown-20240501-100000.log.gz"; got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
+
+func TestStreamToSlack_archive(t *testing.T) {
+	for _, compress := range []bool{false, true} {
+		var texts []string
+		var files []slack.PostFileParam
+		var contents [][]byte
+		cl := &CLI{
+			outStream:   new(bytes.Buffer),
+			errStream:   new(bytes.Buffer),
+			inputStream: strings.NewReader("start\nERROR: disk full\nhealthcheck ok\n"),
+			sClient: &fakeSlackClient{
+				FakePostText: func(ctx context.Context, param *slack.PostTextParam) error {
+					texts = append(texts, param.Text)
+					return nil
+				},
+				FakePostFile: func(ctx context.Context, param *slack.PostFileParam, content []byte) error {
+					files = append(files, *param)
+					contents = append(contents, content)
+					return nil
+				},
+			},
+			conf: config.NewConfig(),
+		}
+		cl.conf.Duration = time.Hour
+		cl.conf.ChannelID = "C12345678"
+		cl.conf.FilterInclude = []string{"ERROR"}
+		cl.conf.Archive = true
+		cl.conf.ArchiveGzip = compress
+
+		status := cl.streamToSlack(t.Context(), &cliOptions{})
+		if status != ExitCodeOK {
+			t.Errorf("ExitStatus=%d, want %d", status, ExitCodeOK)
+		}
+
+		if len(texts) != 1 || texts[0] != "ERROR: disk full\n" {
+			t.Errorf("unexpected texts: %q", texts)
+		}
+		if len(files) != 1 {
+			t.Fatalf("expected 1 file, got %d", len(files))
+		}
+
+		if !strings.HasPrefix(files[0].Filename, "job-") {
+			t.Errorf("expected %q to start with %q", files[0].Filename, "job-")
+		}
+		if files[0].ChannelID != "C12345678" {
+			t.Errorf("got %s, want %s", files[0].ChannelID, "C12345678")
+		}
+
+		content := contents[0]
+		if compress {
+			if !strings.HasSuffix(files[0].Filename, ".log.gz") {
+				t.Errorf("expected %q to end with %q", files[0].Filename, ".log.gz")
+			}
+			zr, err := gzip.NewReader(bytes.NewReader(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err = io.ReadAll(zr)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// The archive has the lines filtered out too
+		expected := "start\nERROR: disk full\nhealthcheck ok\n"
+		if string(content) != expected {
+			t.Errorf("got %q, want %q", content, expected)
+		}
+	}
+}
+
+func TestRun_archiveWithoutChannelID(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	errStream := new(bytes.Buffer)
+	cl := NewCLI(new(bytes.Buffer), errStream, strings.NewReader("abc\n"), false)
+
+	// The archive would be private without a channel to share it in
+	status := cl.Run([]string{"notify_slack", "-token", "xoxb-token", "-channel", "#test", "-archive"})
+	if status != ExitCodeFail {
+		t.Errorf("ExitStatus=%d, want %d", status, ExitCodeFail)
+	}
+
+	expected := "must specify Slack token and channel id to upload the archive"
+	if !strings.Contains(errStream.String(), expected) {
+		t.Errorf("expected %q to contain %q", errStream.String(), expected)
+	}
+}
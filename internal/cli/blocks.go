@@ -0,0 +1,75 @@
+package cli
+
+import (
+	"context"
+	"time"
+	"unicode/utf8"
+
+	"github.com/catatsuy/notify_slack/internal/slack"
+)
+
+// defaultBlocksTitle is the header of output posted as Block Kit when
+// neither a title nor a command is given.
+const defaultBlocksTitle = "Output"
+
+// outputBlocks renders a flush as Block Kit: a header with title, a context
+// with the host and time, and the output in a code block. Output is shown
+// next to a bar of color if set. Text is the fallback shown in
+// notifications.
+func outputBlocks(base slack.PostTextParam, title, host string, t time.Time, output, color string) *slack.PostTextParam {
+	var elements []*slack.TextObject
+	if host != "" {
+		elements = append(elements, slack.NewMrkdwn(":computer: "+slack.EscapeText(host)))
+	}
+	elements = append(elements, slack.NewMrkdwn(slackDate(t)))
+
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(truncateText(title, slack.MaxHeaderTextLength)),
+		slack.NewContextBlock(elements...),
+		slack.NewSectionBlock(slack.NewMrkdwn(slack.CodeBlock(output))),
+	}
+
+	param := base
+	param.Text = slack.EscapeText(output)
+	if color != "" {
+		param.Attachments = []slack.Attachment{{Color: color, Fallback: param.Text, Blocks: blocks}}
+	} else {
+		param.Blocks = blocks
+	}
+
+	return &param
+}
+
+// postBlocks posts output as Block Kit, split into several messages if it
+// doesn't fit in one section. The mention, if any, is put in the text and
+// in a section before the first message.
+func (c *CLI) postBlocks(ctx context.Context, base slack.PostTextParam, settings *streamSettings, output string, a alert) error {
+	limit := slack.MaxSectionTextLength
+	if c.conf.MessageLimit > 0 {
+		limit = min(limit, c.conf.MessageLimit)
+	}
+
+	for i, text := range slack.FormatCode.Chunks(output, limit) {
+		p := outputBlocks(base, settings.title, settings.host, time.Now(), text, a.color)
+		if i == 0 && a.mention != "" {
+			// Mentions only notify people in the text, and are hidden by
+			// the blocks otherwise
+			p.Text = alert{mention: a.mention, text: p.Text}.message()
+			p.Blocks = append([]slack.Block{slack.NewSectionBlock(slack.NewMrkdwn(a.mention))}, p.Blocks...)
+		}
+		if err := c.sClient.PostText(ctx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// truncateText cuts text to at most limit characters, ending with an
+// ellipsis if cut.
+func truncateText(text string, limit int) string {
+	if utf8.RuneCountInString(text) <= limit {
+		return text
+	}
+	runes := []rune(text)
+	return string(runes[:limit-1]) + "…"
+}
@@ -0,0 +1,100 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/catatsuy/notify_slack/internal/config"
+	"github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestOutputBlocks(t *testing.T) {
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	param := outputBlocks(slack.PostTextParam{Channel: "#test"}, "make deploy", "web1", now, "a <b>\n", "")
+
+	expected := &slack.PostTextParam{
+		Channel: "#test",
+		Text:    "a &lt;b&gt;\n",
+		Blocks: []slack.Block{
+			slack.NewHeaderBlock("make deploy"),
+			slack.NewContextBlock(
+				slack.NewMrkdwn(":computer: web1"),
+				slack.NewMrkdwn("<!date^1767323045^{date_num} {time_secs}|2026-01-02T03:04:05Z>"),
+			),
+			slack.NewSectionBlock(slack.NewMrkdwn("```\na &lt;b&gt;\n```")),
+		},
+	}
+	if diff := cmp.Diff(expected, param); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+
+	// The blocks are put in an attachment to show the color
+	param = outputBlocks(slack.PostTextParam{}, strings.Repeat("x", 200), "", now, "a\n", slack.ColorDanger)
+	if len(param.Blocks) != 0 || len(param.Attachments) != 1 {
+		t.Fatalf("expected only 1 attachment; got %+v", param)
+	}
+	if param.Attachments[0].Color != slack.ColorDanger {
+		t.Errorf("got %s, want %s", param.Attachments[0].Color, slack.ColorDanger)
+	}
+	blocks := param.Attachments[0].Blocks
+	if n := len([]rune(blocks[0].Text.Text)); n != slack.MaxHeaderTextLength {
+		t.Errorf("got %d, want %d", n, slack.MaxHeaderTextLength)
+	}
+	if n := len(blocks[1].Elements); n != 1 {
+		t.Errorf("got %d, want %d", n, 1)
+	}
+}
+
+func TestStreamToSlack_blocks(t *testing.T) {
+	var params []slack.PostTextParam
+	cl := &CLI{
+		outStream:   new(bytes.Buffer),
+		errStream:   new(bytes.Buffer),
+		inputStream: strings.NewReader("abc\ndef\n"),
+		sClient: &fakeSlackClient{
+			FakePostText: func(ctx context.Context, param *slack.PostTextParam) error {
+				params = append(params, *param)
+				return nil
+			},
+		},
+		conf: config.NewConfig(),
+	}
+	cl.conf.Duration = time.Hour
+	cl.conf.MessageLimit = 14
+	cl.conf.Format = "blocks"
+	cl.conf.SummaryTitle = "nightly backup"
+
+	status := cl.streamToSlack(t.Context(), &cliOptions{alertPattern: "def", alertMention: "<!here>"})
+	if status != ExitCodeOK {
+		t.Errorf("ExitStatus=%d, want %d", status, ExitCodeOK)
+	}
+
+	// Each message is a whole code block within the limit
+	if len(params) != 2 {
+		t.Fatalf("expected 2 messages; got %d", len(params))
+	}
+	if params[0].Text != "<!here>\nabc\n" {
+		t.Errorf("got %q, want %q", params[0].Text, "<!here>\nabc\n")
+	}
+	if diff := cmp.Diff(slack.NewSectionBlock(slack.NewMrkdwn("<!here>")), params[0].Blocks[0]); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+	blocks := params[1].Attachments[0].Blocks
+	if blocks[0].Text.Text != "nightly backup" {
+		t.Errorf("got %s, want %s", blocks[0].Text.Text, "nightly backup")
+	}
+	// The emoji would show literally in the code block
+	if blocks[2].Text.Text != "```\ndef\n```" {
+		t.Errorf("got %q, want %q", blocks[2].Text.Text, "```\ndef\n```")
+	}
+
+	status = cl.streamToSlack(t.Context(), &cliOptions{update: true})
+	if status != ExitCodeFail {
+		t.Errorf("ExitStatus=%d, want %d", status, ExitCodeFail)
+	}
+}
@@ -2,6 +2,7 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -10,10 +11,18 @@ import (
 	"os/signal"
 	"runtime"
 	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/catatsuy/notify_slack/internal/ansi"
 	"github.com/catatsuy/notify_slack/internal/config"
+	"github.com/catatsuy/notify_slack/internal/filter"
+	"github.com/catatsuy/notify_slack/internal/follow"
+	"github.com/catatsuy/notify_slack/internal/redact"
+	"github.com/catatsuy/notify_slack/internal/relay"
 	"github.com/catatsuy/notify_slack/internal/slack"
 	"github.com/catatsuy/notify_slack/internal/throttle"
 )
@@ -40,6 +49,7 @@ type CLI struct {
 
 	sClient    slack.Slack
 	conf       *config.Config
+	redactor   *redact.Redactor
 	appVersion string
 }
 
@@ -67,9 +77,30 @@ type cliOptions struct {
 	snippetMode    bool
 	debugMode      bool
 	filename       string
+	update         bool
+	updateLines    int
+
+	thread          bool
+	threadHeader    string
+	threadBroadcast bool
+
+	// command is run and its output is posted when given after "--"
+	command      []string
+	stderrPrefix string
+	ansi         string
+
+	alertPattern string
+	alertMention string
+
+	follow      []string
+	followLines int
 }
 
 func (c *CLI) Run(args []string) int {
+	if len(args) > 1 && args[1] == "serve" {
+		return c.runServe(args[1:])
+	}
+
 	opts, err := c.parseFlags(args)
 	if err != nil {
 		return ExitCodeParseFlagError
@@ -85,14 +116,27 @@ func (c *CLI) Run(args []string) int {
 		return ExitCodeFail
 	}
 
+	// Files to follow may be given in the config file
+	if opts.filename == "" && len(opts.command) == 0 && len(c.followSources(opts)) == 0 && c.isStdinTerminal {
+		fmt.Fprintln(c.errStream, "No input file specified")
+		return ExitCodeParseFlagError
+	}
+
 	logger := c.createLogger(opts.debugMode)
 	ctx := context.Background()
 
+	redactor, err := redact.New(c.conf.RedactPatterns, logger)
+	if err != nil {
+		fmt.Fprintln(c.errStream, err)
+		return ExitCodeFail
+	}
+	c.redactor = redactor
+
 	if opts.filename != "" || opts.snippetMode {
 		return c.handleSnippetMode(ctx, opts, logger)
 	}
 
-	return c.handleTextMode(ctx, logger)
+	return c.handleTextMode(ctx, opts, logger)
 }
 
 func (c *CLI) parseFlags(args []string) (*cliOptions, error) {
@@ -116,6 +160,25 @@ func (c *CLI) parseFlags(args []string) (*cliOptions, error) {
 
 	// Process remaining arguments
 	argv := flags.Args()
+
+	if len(opts.follow) > 0 {
+		if opts.snippetMode || len(argv) > 0 {
+			fmt.Fprintln(c.errStream, "You cannot use -follow with snippet mode, a file or a command")
+			return nil, fmt.Errorf("-follow specified with another input")
+		}
+		return opts, nil
+	}
+
+	// Everything after "--" is a command to run
+	if len(argv) > 0 && args[len(args)-len(argv)-1] == "--" {
+		if opts.snippetMode {
+			fmt.Fprintln(c.errStream, "You cannot run a command in snippet mode")
+			return nil, fmt.Errorf("command specified in snippet mode")
+		}
+		opts.command = argv
+		return opts, nil
+	}
+
 	if err := c.processArguments(argv, opts, flags); err != nil {
 		return nil, err
 	}
@@ -124,18 +187,54 @@ func (c *CLI) parseFlags(args []string) (*cliOptions, error) {
 }
 
 func (c *CLI) setupFlags(flags *flag.FlagSet, opts *cliOptions) {
-	flags.StringVar(&c.conf.Channel, "channel", "", "specify channel (unavailable for new Incoming Webhooks)")
+	flags.Var(&channelsFlag{conf: c.conf}, "channel", "specify channel (unavailable for new Incoming Webhooks); can be repeated to post the same text to each of them with the token")
 	flags.StringVar(&c.conf.ChannelID, "channel-id", "", "specify channel id (for uploading a file)")
-	flags.StringVar(&c.conf.SlackURL, "slack-url", "", "slack url (Incoming Webhooks URL)")
-	flags.StringVar(&c.conf.Token, "token", "", "token (for uploading to snippet)")
+	flags.Var(&slackURLsFlag{conf: c.conf}, "slack-url", "slack url (Incoming Webhooks URL); can be repeated to post the same text to each of them")
+	flags.StringVar(&c.conf.Token, "token", "", "token (for uploading to snippet, or for posting with chat.postMessage)")
+	flags.StringVar(&c.conf.Via, "via", "", "post through a relay started with notify_slack serve, e.g. unix:///run/notify_slack.sock or http://127.0.0.1:8125")
 	flags.StringVar(&c.conf.Username, "username", "", "specify username (unavailable for new Incoming Webhooks)")
 	flags.StringVar(&c.conf.IconEmoji, "icon-emoji", "", "specify icon emoji (unavailable for new Incoming Webhooks)")
-	flags.DurationVar(&c.conf.Duration, "interval", time.Second, "interval")
+	flags.DurationVar(&c.conf.Duration, "interval", time.Second, "interval; 0 disables flushing on intervals")
+	flags.IntVar(&c.conf.FlushBytes, "flush-bytes", 0, "also flush when the buffered output reaches this many bytes")
+	flags.IntVar(&c.conf.FlushLines, "flush-lines", 0, "also flush when the buffered output reaches this many lines")
+	flags.DurationVar(&c.conf.FlushQuiet, "flush-quiet", 0, "also flush when no output has been read for this duration")
+	flags.DurationVar(&c.conf.FlushMaxDelay, "flush-max-delay", 0, "flush output at the latest this long after it was read (use with -flush-quiet)")
+	flags.BoolVar(&c.conf.FlushFirstLine, "flush-first-line", false, "flush the first line immediately")
+	flags.StringVar(&c.conf.Dedupe, "dedupe", "", "fold repeated lines into one line with a counter: off, exact, or normalize to also fold lines differing only in numbers (default off)")
+	flags.StringVar(&c.conf.OnError, "on-error", "", "what to do when posting to Slack fails: continue or abort (default continue)")
+	flags.IntVar(&c.conf.MessageLimit, "message-limit", 0, fmt.Sprintf("maximum number of characters per message; longer output is split into several messages (default %d)", slack.DefaultTextLimit))
+	flags.StringVar(&c.conf.Format, "format", "", "how output is put into messages: raw to render it as mrkdwn, escape to escape &, < and > so that nothing becomes a link or mention, code to escape it and put it in a code block, or blocks to post it as Block Kit with a header, the host and time (default raw)")
+	flags.IntVar(&c.conf.MaxLineBytes, "max-line-bytes", 0, fmt.Sprintf("truncate lines longer than this many bytes; a negative value keeps lines of any length (default %d)", throttle.DefaultMaxLineBytes))
+	flags.IntVar(&c.conf.RetryMaxAttempts, "retry-max-attempts", 0, fmt.Sprintf("maximum number of attempts for a request rate limited or failed by Slack; 1 disables retries (default %d)", slack.DefaultRetryMaxAttempts))
+	flags.DurationVar(&c.conf.RetryMaxElapsed, "retry-max-elapsed", 0, fmt.Sprintf("give up retrying a request after this duration (default %s)", slack.DefaultRetryMaxElapsed))
 	flags.StringVar(&opts.tomlFile, "c", "", "config file name")
 	flags.StringVar(&opts.uploadFilename, "filename", "", "specify a file name (for uploading to snippet)")
 	flags.StringVar(&opts.filetype, "filetype", "", "[compatible] specify a filetype for uploading to snippet. This option is maintained for compatibility. Please use -snippet-type instead.")
 	flags.StringVar(&opts.filetype, "snippet-type", "", "specify a snippet_type (for uploading to snippet)")
 	flags.BoolVar(&opts.snippetMode, "snippet", false, "switch to snippet uploading mode")
+	flags.IntVar(&c.conf.SnippetLines, "snippet-lines", 0, "upload output flushed at once with more than this many lines as a snippet, with its first and last lines as a preview (requires token and channel id)")
+	flags.IntVar(&c.conf.SnippetPreviewLines, "snippet-preview-lines", 0, fmt.Sprintf("number of lines shown from the start and from the end of output uploaded with -snippet-lines (default %d)", defaultSnippetPreviewLines))
+	flags.BoolVar(&opts.update, "update", false, "keep the output in a single message updated with the latest lines (requires token and channel)")
+	flags.IntVar(&opts.updateLines, "update-lines", defaultUpdateLines, "number of latest lines shown in the message in update mode")
+	flags.BoolVar(&opts.thread, "thread", false, "post the first message as a parent and the rest as replies in its thread (requires token and channel)")
+	flags.StringVar(&opts.threadHeader, "thread-header", "", "post this text as the parent message in thread mode instead of the first output")
+	flags.BoolVar(&opts.threadBroadcast, "thread-broadcast", false, "also send the final reply to the channel in thread mode")
+	flags.Var((*stringsFlag)(&c.conf.FilterInclude), "include", "only post lines matching this regular expression (can be repeated)")
+	flags.Var((*stringsFlag)(&c.conf.FilterExclude), "exclude", "don't post lines matching this regular expression (can be repeated)")
+	flags.IntVar(&c.conf.FilterContext, "context", 0, "also post this many lines before and after each line matching -include")
+	flags.StringVar(&opts.alertPattern, "alert", "", "highlight lines matching this regular expression and mention -alert-mention")
+	flags.StringVar(&opts.alertMention, "alert-mention", "", "mention to prepend when a line matches -alert, e.g. <!here>, <!subteam^ID> or <@U123>")
+	flags.DurationVar(&c.conf.AlertIfSilent, "alert-if-silent", 0, "post a warning with the last line when no line has been read for this duration")
+	flags.BoolVar(&c.conf.AlertRecovery, "alert-recovery", false, "also post a message when lines are read again after -alert-if-silent warned")
+	flags.DurationVar(&c.conf.AlertCooldown, "alert-cooldown", 0, fmt.Sprintf("minimum interval between mentions by the same alert rule (default %s)", defaultAlertCooldown))
+	flags.StringVar(&opts.ansi, "ansi", "", "how to post ANSI escape sequences and text overwritten with carriage returns: strip, keep, or auto to strip them unless stdin is a terminal (default auto)")
+	flags.Var((*stringsFlag)(&opts.follow), "follow", "post lines appended to this file, following it across truncation and rotation like tail -F (can be repeated, and can be a glob)")
+	flags.IntVar(&opts.followLines, "follow-lines", 0, "start -follow with the last lines of the file instead of its end")
+	flags.StringVar(&opts.stderrPrefix, "stderr-prefix", "", "prefix for lines written to stderr by the command given after --")
+	flags.BoolVar(&c.conf.Archive, "archive", false, "upload all the output as a file when the input ends (requires token and channel id)")
+	flags.BoolVar(&c.conf.ArchiveGzip, "archive-gzip", false, "compress the file uploaded with -archive with gzip")
+	flags.BoolVar(&c.conf.Summary, "summary", false, "post a summary with the exit status, duration and host when the input ends")
+	flags.StringVar(&c.conf.SummaryTitle, "summary-title", "", "title of the summary message")
 	flags.BoolVar(&opts.debugMode, "debug", false, "debug mode (for developers)")
 	flags.BoolVar(&opts.version, "version", false, "Print version information and quit")
 }
@@ -153,9 +252,6 @@ func (c *CLI) processArguments(argv []string, opts *cliOptions, flags *flag.Flag
 			fmt.Fprintln(c.errStream, "You cannot pass multiple files")
 			return fmt.Errorf("multiple files specified")
 		}
-	} else if c.isStdinTerminal {
-		fmt.Fprintln(c.errStream, "No input file specified")
-		return fmt.Errorf("no input file specified")
 	}
 	return nil
 }
@@ -182,17 +278,34 @@ func (c *CLI) createLogger(debugMode bool) *slog.Logger {
 }
 
 func (c *CLI) handleSnippetMode(ctx context.Context, opts *cliOptions, logger *slog.Logger) int {
+	if c.conf.Via != "" {
+		// The relay uploads with its own token
+		client, err := relay.NewClient(c.conf.Via, logger)
+		if err != nil {
+			fmt.Fprintln(c.errStream, err)
+			return ExitCodeFail
+		}
+		c.sClient = client
+
+		if err := c.uploadSnippet(ctx, opts.filename, opts.uploadFilename, opts.filetype); err != nil {
+			fmt.Fprintln(c.errStream, err)
+			return ExitCodeFail
+		}
+		return ExitCodeOK
+	}
+
 	if c.conf.Token == "" {
 		fmt.Fprintln(c.errStream, "must specify Slack token for uploading to snippet")
 		return ExitCodeFail
 	}
 
-	var err error
-	c.sClient, err = slack.NewClientForPostFile(c.conf.Token, logger)
+	client, err := slack.NewClientForPostFile(c.conf.Token, logger)
 	if err != nil {
 		fmt.Fprintln(c.errStream, err)
 		return ExitCodeFail
 	}
+	client.Retry = c.retryPolicy()
+	c.sClient = client
 
 	if err := c.uploadSnippet(ctx, opts.filename, opts.uploadFilename, opts.filetype); err != nil {
 		fmt.Fprintln(c.errStream, err)
@@ -202,57 +315,761 @@ func (c *CLI) handleSnippetMode(ctx context.Context, opts *cliOptions, logger *s
 	return ExitCodeOK
 }
 
-func (c *CLI) handleTextMode(ctx context.Context, logger *slog.Logger) int {
-	if c.conf.SlackURL == "" {
-		fmt.Fprintln(c.errStream, "must specify Slack URL")
+func (c *CLI) handleTextMode(ctx context.Context, opts *cliOptions, logger *slog.Logger) int {
+	if c.conf.MessageLimit <= 0 {
+		c.conf.MessageLimit = slack.DefaultTextLimit
+	}
+
+	if opts.update && opts.thread {
+		fmt.Fprintln(c.errStream, "cannot use update mode and thread mode together")
 		return ExitCodeFail
 	}
 
+	// These modes post to a single destination
+	if len(c.slackURLs())+len(c.fanOutChannels()) > 1 {
+		switch {
+		case opts.update || opts.thread:
+			fmt.Fprintln(c.errStream, "cannot use update mode and thread mode with several Slack URLs or channels")
+			return ExitCodeFail
+		case c.conf.Via != "":
+			fmt.Fprintln(c.errStream, "cannot use -via with several Slack URLs or channels; give them to the relay instead")
+			return ExitCodeFail
+		case c.routesFollow(opts):
+			fmt.Fprintln(c.errStream, "cannot post followed files to their own channels with several Slack URLs or channels")
+			return ExitCodeFail
+		}
+	}
+
+	// The relay uploads snippets with its own token and channel ID
+	if c.conf.SnippetLines > 0 && c.conf.Via == "" && (c.conf.Token == "" || c.conf.ChannelID == "") {
+		fmt.Fprintln(c.errStream, "must specify Slack token and channel id to upload large output as a snippet")
+		return ExitCodeFail
+	}
+	if c.conf.Archive && c.conf.Via == "" && (c.conf.Token == "" || c.conf.ChannelID == "") {
+		fmt.Fprintln(c.errStream, "must specify Slack token and channel id to upload the archive")
+		return ExitCodeFail
+	}
+
+	if c.conf.Via != "" {
+		if opts.update || opts.thread {
+			fmt.Fprintln(c.errStream, "cannot use update mode and thread mode with -via")
+			return ExitCodeFail
+		}
+		client, err := relay.NewClient(c.conf.Via, logger)
+		if err != nil {
+			fmt.Fprintln(c.errStream, err)
+			return ExitCodeFail
+		}
+		c.sClient = client
+
+		return c.streamToSlack(ctx, opts)
+	}
+
+	var client *slack.Client
 	var err error
-	c.sClient, err = slack.NewClient(c.conf.SlackURL, logger)
+	if opts.update || opts.thread {
+		if c.conf.Token == "" || c.postChannel() == "" {
+			fmt.Fprintln(c.errStream, "must specify Slack token and channel for update mode and thread mode")
+			return ExitCodeFail
+		}
+		client, err = slack.NewClientForPostFile(c.conf.Token, logger)
+	} else if c.routesFollow(opts) {
+		// Incoming Webhooks can't choose the channel
+		if c.conf.Token == "" {
+			fmt.Fprintln(c.errStream, "must specify Slack token to post followed files to their own channels")
+			return ExitCodeFail
+		}
+		client, err = slack.NewClientForPostFile(c.conf.Token, logger)
+	} else if urls, channels := c.slackURLs(), c.fanOutChannels(); len(urls)+len(channels) > 1 {
+		fanOut, err := c.newFanOut(urls, channels, logger)
+		if err != nil {
+			fmt.Fprintln(c.errStream, err)
+			return ExitCodeFail
+		}
+
+		return c.streamToFanOut(ctx, opts, fanOut)
+	} else if len(urls) == 1 {
+		client, err = slack.NewClient(urls[0], logger)
+	} else if c.conf.Token != "" && c.postChannel() != "" {
+		// Without an Incoming Webhooks URL, post with chat.postMessage
+		client, err = slack.NewClientForPostFile(c.conf.Token, logger)
+	} else {
+		fmt.Fprintln(c.errStream, "must specify Slack URL, or Slack token and channel")
+		return ExitCodeFail
+	}
 	if err != nil {
 		fmt.Fprintln(c.errStream, err)
 		return ExitCodeFail
 	}
+	client.Retry = c.retryPolicy()
+	// Large output is uploaded as a snippet with the token even when
+	// messages are posted to an Incoming Webhooks URL
+	client.Token = c.conf.Token
+	c.sClient = client
 
-	return c.streamToSlack(ctx)
+	return c.streamToSlack(ctx, opts)
 }
 
-func (c *CLI) streamToSlack(ctx context.Context) int {
-	copyStdin := io.TeeReader(c.inputStream, c.outStream)
-	ex := throttle.NewExec(copyStdin)
+// streamToFanOut is streamToSlack posting to all the destinations of
+// fanOut. It waits for the destinations still posting before returning.
+func (c *CLI) streamToFanOut(ctx context.Context, opts *cliOptions, fanOut *slack.FanOut) int {
+	c.sClient = fanOut
+
+	exitCode := c.streamToSlack(ctx, opts)
+	if err := fanOut.Close(); err != nil {
+		fmt.Fprintln(c.errStream, err)
+		exitCode = ExitCodeFail
+	}
+	return exitCode
+}
 
-	ctx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
-	defer stop()
+// commandLine returns the command line of args with secrets redacted, as
+// it is shown in messages.
+func (c *CLI) commandLine(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	line := quoteCommand(args)
+	if c.redactor != nil {
+		line = string(c.redactor.Redact([]byte(line)))
+	}
+	return line
+}
+
+// slackURLs returns all the Incoming Webhooks URLs to post to.
+func (c *CLI) slackURLs() []string {
+	var urls []string
+	if c.conf.SlackURL != "" {
+		urls = append(urls, c.conf.SlackURL)
+	}
+	return append(urls, c.conf.SlackURLs...)
+}
+
+// fanOutChannels returns the channels to post to with the token in
+// addition to the Incoming Webhooks URLs, or nil if only one channel is
+// given. Without URLs, the main channel is one of them.
+func (c *CLI) fanOutChannels() []string {
+	if len(c.conf.Channels) == 0 {
+		return nil
+	}
+	if len(c.slackURLs()) > 0 || c.postChannel() == "" {
+		return c.conf.Channels
+	}
+	return append([]string{c.postChannel()}, c.conf.Channels...)
+}
+
+// newFanOut returns a client posting to all of urls, and to all of channels
+// with the token, at the same time. URL destinations are named by their
+// position, since the URLs are secrets.
+func (c *CLI) newFanOut(urls, channels []string, logger *slog.Logger) (*slack.FanOut, error) {
+	destinations := make([]slack.Destination, 0, len(urls))
+	for i, u := range urls {
+		client, err := slack.NewClient(u, logger)
+		if err != nil {
+			return nil, err
+		}
+		client.Retry = c.retryPolicy()
+		// Large output is uploaded as a snippet with the token
+		client.Token = c.conf.Token
+
+		destinations = append(destinations, slack.Destination{
+			Name:  fmt.Sprintf("slack url #%d", i+1),
+			Slack: client,
+		})
+	}
+
+	if len(channels) > 0 {
+		if c.conf.Token == "" {
+			return nil, errors.New("must specify Slack token to post to several channels")
+		}
+		client, err := slack.NewClientForPostFile(c.conf.Token, logger)
+		if err != nil {
+			return nil, err
+		}
+		client.Retry = c.retryPolicy()
+
+		for _, channel := range channels {
+			destinations = append(destinations, slack.Destination{
+				Name:    channel,
+				Channel: channel,
+				Slack:   client,
+			})
+		}
+	}
+
+	return slack.NewFanOut(destinations, logger), nil
+}
+
+// postChannel returns the channel for chat.postMessage, which accepts
+// either a channel name or a channel ID.
+func (c *CLI) postChannel() string {
+	if c.conf.Channel != "" {
+		return c.conf.Channel
+	}
+	return c.conf.ChannelID
+}
+
+// retryPolicy returns the retry policy for Slack clients with the configured
+// limits applied.
+func (c *CLI) retryPolicy() slack.RetryPolicy {
+	policy := slack.DefaultRetryPolicy()
+	if c.conf.RetryMaxAttempts > 0 {
+		policy.MaxAttempts = c.conf.RetryMaxAttempts
+	}
+	if c.conf.RetryMaxElapsed > 0 {
+		policy.MaxElapsed = c.conf.RetryMaxElapsed
+	}
+	return policy
+}
+
+// streamSettings are the options for streaming parsed and checked before
+// any input is read.
+type streamSettings struct {
+	errorPolicy throttle.ErrorPolicy
+	dedupe      throttle.DedupeMode
+	stripANSI   bool
+	format      slack.TextFormat
+
+	// blocks posts each flush as Block Kit with a header of title and a
+	// context of host
+	blocks bool
+	title  string
+	host   string
+
+	// archive is shared by all inputs if set
+	archive *archive
+}
+
+func (c *CLI) parseStreamSettings(opts *cliOptions) (*streamSettings, error) {
+	var s streamSettings
+	var err error
+
+	s.errorPolicy, err = parseErrorPolicy(c.conf.OnError)
+	if err != nil {
+		return nil, err
+	}
+	s.dedupe, err = parseDedupeMode(c.conf.Dedupe)
+	if err != nil {
+		return nil, err
+	}
+	s.stripANSI, err = c.stripANSI(opts.ansi)
+	if err != nil {
+		return nil, err
+	}
+	s.format, err = parseTextFormat(c.conf.Format)
+	if err != nil {
+		return nil, err
+	}
+	if c.conf.Format == "blocks" {
+		if opts.update || opts.thread {
+			return nil, fmt.Errorf("incorrect value to format option: %s: not supported in update mode and thread mode", c.conf.Format)
+		}
+		s.blocks = true
+		s.title = c.conf.SummaryTitle
+		if s.title == "" {
+			s.title = defaultBlocksTitle
+			if len(opts.command) > 0 {
+				s.title = c.commandLine(opts.command)
+			}
+		}
+		// The messages are still useful without it
+		s.host, _ = os.Hostname()
+	}
+
+	// Check the patterns now. Each input gets its own alerter and filter.
+	if _, err := c.newAlerter(opts); err != nil {
+		return nil, err
+	}
+	if _, err := c.newFilter(); err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// streamInput is an input posted to a channel.
+type streamInput struct {
+	reader io.Reader
+	// channel overrides the channel of the messages if set
+	channel string
+}
+
+func (c *CLI) streamToSlack(ctx context.Context, opts *cliOptions) int {
+	settings, err := c.parseStreamSettings(opts)
+	if err != nil {
+		fmt.Fprintln(c.errStream, err)
+		return ExitCodeFail
+	}
 
 	param := &slack.PostTextParam{
 		Channel:   c.conf.Channel,
 		Username:  c.conf.Username,
 		IconEmoji: c.conf.IconEmoji,
 	}
+	if opts.update || opts.thread || c.routesFollow(opts) || len(c.slackURLs()) == 0 {
+		// chat.postMessage accepts a channel ID as well as a channel name
+		param.Channel = c.postChannel()
+	}
+
+	start := time.Now()
+	counter := &outputCounter{}
+
+	if c.conf.Archive {
+		settings.archive, err = newArchive(c.conf.ArchiveGzip)
+		if err != nil {
+			fmt.Fprintln(c.errStream, err)
+			return ExitCodeFail
+		}
+		defer settings.archive.remove()
+	}
+
+	var inputs []streamInput
+	var child *childCommand
+	if len(opts.command) > 0 {
+		child, err = startCommand(opts.command, c.inputStream, c.outStream, c.errStream, opts.stderrPrefix)
+		if err != nil {
+			fmt.Fprintln(c.errStream, err)
+			return ExitCodeFail
+		}
+		// Signals are forwarded to the command. Its output is read until
+		// it exits.
+		inputs = append(inputs, streamInput{reader: io.TeeReader(child.output, counter)})
+	} else if routes := followRoutes(c.followSources(opts)); len(routes) > 0 {
+		// The files of several routes are copied at the same time
+		copyOutput := &lockedWriter{w: io.MultiWriter(c.outStream, counter)}
+		for _, route := range routes {
+			follower, err := follow.NewMulti(route.patterns, opts.followLines, route.prefix, c.createLogger(opts.debugMode))
+			if err != nil {
+				fmt.Fprintln(c.errStream, err)
+				return ExitCodeFail
+			}
+			defer follower.Close()
+			follower.Start()
+
+			// Closing the input stops following the files on signals
+			inputs = append(inputs, streamInput{
+				reader: &teeReadCloser{
+					Reader: io.TeeReader(follower, copyOutput),
+					Closer: follower,
+				},
+				channel: route.channel,
+			})
+		}
+
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+		defer stop()
+	} else {
+		copyStdin := io.TeeReader(c.inputStream, io.MultiWriter(c.outStream, counter))
+		inputs = append(inputs, streamInput{reader: copyStdin})
+
+		var stop context.CancelFunc
+		ctx, stop = signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+		defer stop()
+	}
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(inputs))
+	for i, input := range inputs {
+		inputParam := *param
+		if input.channel != "" {
+			inputParam.Channel = input.channel
+		}
+		wg.Go(func() {
+			errs[i] = c.runStream(ctx, opts, settings, input.reader, inputParam)
+		})
+	}
+	wg.Wait()
+
+	streamErr := errors.Join(errs...)
+	if streamErr != nil {
+		fmt.Fprintln(c.errStream, streamErr)
+	}
+
+	summary := newJobSummary(c.conf.SummaryTitle, c.commandLine(opts.command), start)
+
+	// Uploaded once all inputs have ended, before the summary
+	if settings.archive != nil {
+		if err := c.uploadArchive(context.WithoutCancel(ctx), settings.archive, summary.host, start); err != nil {
+			fmt.Fprintln(c.errStream, err)
+			streamErr = errors.Join(streamErr, err)
+		}
+	}
+
+	exitCode := ExitCodeOK
+	if child != nil {
+		exitCode = child.wait()
+		summary.exitCode = exitCode
+		summary.exitKnown = true
+	}
+	// The exit status of the command takes precedence so that failures of
+	// the command itself are not hidden
+	if streamErr != nil && exitCode == ExitCodeOK {
+		exitCode = ExitCodeFail
+	}
+
+	if c.conf.Summary {
+		summary.end = time.Now()
+		summary.lines = counter.lines.Load()
+		summary.bytes = counter.bytes.Load()
+
+		if err := c.sClient.PostText(context.WithoutCancel(ctx), summary.param(*param)); err != nil {
+			fmt.Fprintln(c.errStream, err)
+			if exitCode == ExitCodeOK {
+				exitCode = ExitCodeFail
+			}
+		}
+	}
+
+	return exitCode
+}
+
+// runStream posts the lines read from input with param through a
+// throttle.Exec until the input ends or ctx is done.
+func (c *CLI) runStream(ctx context.Context, opts *cliOptions, settings *streamSettings, input io.Reader, param slack.PostTextParam) error {
+	alerts, err := c.newAlerter(opts)
+	if err != nil {
+		return err
+	}
+	if alerts != nil && (settings.blocks || settings.format == slack.FormatCode) {
+		alerts.plain = true
+	}
+	lineFilter, err := c.newFilter()
+	if err != nil {
+		return err
+	}
+
+	ex := throttle.NewExec(input)
+
+	// The local copy of the output is left as is for the terminal
+	if settings.stripANSI {
+		ex.AddStage(func(line []byte, emit func([]byte)) {
+			emit(ansi.Normalize(line))
+		})
+	}
+	if c.redactor != nil {
+		secrets := c.redactor.NewStream()
+		ex.AddStage(func(line []byte, emit func([]byte)) {
+			if redacted, ok := secrets.Line(line); ok {
+				emit(redacted)
+			}
+		})
+	}
+	// The archive has all lines, including the ones filtered out
+	if settings.archive != nil {
+		ex.AddStage(settings.archive.stage)
+	}
+	if lineFilter != nil {
+		ex.AddStage(lineFilter.Line)
+	}
 
 	flushCallback := func(ctx context.Context, output string) error {
-		param.Text = output
-		return c.sClient.PostText(context.WithoutCancel(ctx), param)
+		var a alert
+		if alerts != nil {
+			a = alerts.apply(output)
+			output = a.text
+		}
+
+		if c.conf.SnippetLines > 0 {
+			if lines := strings.Count(output, "\n"); lines > c.conf.SnippetLines {
+				return c.postSnippet(context.WithoutCancel(ctx), output, lines, a.mention, settings.format)
+			}
+		}
+
+		if settings.blocks {
+			return c.postBlocks(context.WithoutCancel(ctx), param, settings, output, a)
+		}
+
+		// Post oversized output as several messages in order
+		for i, text := range settings.format.Split(output, c.conf.MessageLimit) {
+			p := param
+			p.Text = text
+			if a.color != "" {
+				// Show the output next to a colored bar
+				p.Text = ""
+				p.Attachments = []slack.Attachment{{Color: a.color, Fallback: text, Text: text}}
+			}
+			if i == 0 && a.mention != "" {
+				// Mentions only notify people in the text
+				p.Text = alert{mention: a.mention, text: p.Text}.message()
+			}
+			if err := c.sClient.PostText(context.WithoutCancel(ctx), &p); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	finalCallback := flushCallback
+
+	switch {
+	case opts.update:
+		updater := newMessageUpdater(c.sClient, param, opts.updateLines, c.conf.MessageLimit, settings.format)
+		flushCallback = func(ctx context.Context, output string) error {
+			// Colors are only shown in the default mode
+			a := alerts.apply(output)
+			return updater.flush(context.WithoutCancel(ctx), a.text, a.mention)
+		}
+		finalCallback = flushCallback
+	case opts.thread:
+		threader := newThreadPoster(c.sClient, param, opts.threadHeader, c.conf.MessageLimit, settings.format, opts.threadBroadcast)
+		flushCallback = func(ctx context.Context, output string) error {
+			a := alerts.apply(output)
+			return threader.flush(context.WithoutCancel(ctx), a.text, a.mention)
+		}
+		finalCallback = func(ctx context.Context, output string) error {
+			a := alerts.apply(output)
+			return threader.done(context.WithoutCancel(ctx), a.text, a.mention)
+		}
 	}
 
-	done := make(chan struct{})
 	doneCallback := func(ctx context.Context, output string) error {
-		defer func() {
-			go func() {
-				done <- struct{}{}
-			}()
-		}()
-		return flushCallback(context.WithoutCancel(ctx), output)
+		return finalCallback(context.WithoutCancel(ctx), output)
+	}
+
+	if c.conf.AlertIfSilent > 0 {
+		ex.SetSilenceAlert(c.conf.AlertIfSilent, func(ctx context.Context, ev throttle.SilenceEvent) {
+			if ev.Resumed && !c.conf.AlertRecovery {
+				return
+			}
+			if settings.format != slack.FormatRaw {
+				ev.LastLine = slack.EscapeText(ev.LastLine)
+			}
+			p := param
+			p.Text = silenceMessage(ev)
+			// A failed warning doesn't stop the output from being posted
+			if err := c.sClient.PostText(context.WithoutCancel(ctx), &p); err != nil {
+				fmt.Fprintln(c.errStream, err)
+			}
+		})
 	}
 
-	ticker := time.NewTicker(c.conf.Duration)
-	defer ticker.Stop()
+	ex.SetFlushPolicy(throttle.FlushPolicy{
+		MaxBytes:  c.conf.FlushBytes,
+		MaxLines:  c.conf.FlushLines,
+		Quiet:     c.conf.FlushQuiet,
+		MaxDelay:  c.conf.FlushMaxDelay,
+		FirstLine: c.conf.FlushFirstLine,
+	})
+	ex.SetErrorPolicy(settings.errorPolicy)
+	ex.SetDedupe(settings.dedupe)
+	if c.conf.MaxLineBytes != 0 {
+		ex.SetMaxLineBytes(c.conf.MaxLineBytes)
+	}
 
-	ex.Start(ctx, ticker.C, flushCallback, doneCallback)
-	<-done
+	var interval <-chan time.Time
+	if c.conf.Duration > 0 {
+		ticker := time.NewTicker(c.conf.Duration)
+		defer ticker.Stop()
+		interval = ticker.C
+	}
 
-	return ExitCodeOK
+	_, err = ex.Start(ctx, interval, flushCallback, doneCallback)
+	return err
+}
+
+// followSources returns the files to follow given with -follow or in the
+// config file.
+func (c *CLI) followSources(opts *cliOptions) []config.FollowSource {
+	if len(opts.follow) == 0 {
+		return c.conf.Follow
+	}
+
+	sources := make([]config.FollowSource, 0, len(opts.follow))
+	for _, path := range opts.follow {
+		sources = append(sources, config.FollowSource{Path: path})
+	}
+	return sources
+}
+
+// routesFollow reports whether any followed file is posted to its own
+// channel.
+func (c *CLI) routesFollow(opts *cliOptions) bool {
+	return slices.ContainsFunc(c.followSources(opts), func(source config.FollowSource) bool {
+		return source.Channel != ""
+	})
+}
+
+// followRoute is a group of files posted to the same channel.
+type followRoute struct {
+	channel  string
+	patterns []string
+	// prefix tells whether lines are prefixed with their file name, which
+	// is the case when they may come from several files
+	prefix bool
+}
+
+// followRoutes groups sources by channel in the order they are given.
+func followRoutes(sources []config.FollowSource) []*followRoute {
+	var routes []*followRoute
+	byChannel := make(map[string]*followRoute)
+
+	for _, source := range sources {
+		route, ok := byChannel[source.Channel]
+		if !ok {
+			route = &followRoute{channel: source.Channel}
+			byChannel[source.Channel] = route
+			routes = append(routes, route)
+		}
+		route.patterns = append(route.patterns, source.Path)
+	}
+
+	for _, route := range routes {
+		route.prefix = len(route.patterns) > 1 || follow.IsGlob(route.patterns[0])
+	}
+
+	return routes
+}
+
+// lockedWriter serializes writes from several goroutines.
+type lockedWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+// newFilter returns the filter for the include and exclude patterns, or nil
+// if there are none.
+func (c *CLI) newFilter() (*filter.Filter, error) {
+	if len(c.conf.FilterInclude) == 0 && len(c.conf.FilterExclude) == 0 {
+		return nil, nil
+	}
+	return filter.New(c.conf.FilterInclude, c.conf.FilterExclude, c.conf.FilterContext)
+}
+
+// newAlerter returns the alerter for the rules given with -alert or in the
+// config file, or nil if there are none.
+func (c *CLI) newAlerter(opts *cliOptions) (*alerter, error) {
+	rules := c.conf.AlertRules
+	if opts.alertPattern != "" {
+		rules = []config.AlertRule{{
+			Pattern: opts.alertPattern,
+			Mention: opts.alertMention,
+			Color:   slack.ColorDanger,
+		}}
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+	return newAlerter(rules, c.conf.AlertCooldown)
+}
+
+// teeReadCloser closes the reader that Reader copies from.
+type teeReadCloser struct {
+	io.Reader
+	io.Closer
+}
+
+// stringsFlag is a flag that can be given several times.
+type stringsFlag []string
+
+func (f *stringsFlag) String() string {
+	return strings.Join(*f, ", ")
+}
+
+func (f *stringsFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// slackURLsFlag is the -slack-url flag. The first URL is the main one and
+// the others are more destinations.
+type slackURLsFlag struct {
+	conf *config.Config
+}
+
+func (f *slackURLsFlag) String() string {
+	if f.conf == nil {
+		return ""
+	}
+	return strings.Join(append([]string{f.conf.SlackURL}, f.conf.SlackURLs...), ", ")
+}
+
+func (f *slackURLsFlag) Set(value string) error {
+	if f.conf.SlackURL == "" {
+		f.conf.SlackURL = value
+	} else {
+		f.conf.SlackURLs = append(f.conf.SlackURLs, value)
+	}
+	return nil
+}
+
+// channelsFlag is the -channel flag. The first channel is the main one and
+// the others are more destinations.
+type channelsFlag struct {
+	conf *config.Config
+}
+
+func (f *channelsFlag) String() string {
+	if f.conf == nil {
+		return ""
+	}
+	return strings.Join(append([]string{f.conf.Channel}, f.conf.Channels...), ", ")
+}
+
+func (f *channelsFlag) Set(value string) error {
+	if f.conf.Channel == "" {
+		f.conf.Channel = value
+	} else {
+		f.conf.Channels = append(f.conf.Channels, value)
+	}
+	return nil
+}
+
+// parseErrorPolicy parses the on_error option.
+func parseErrorPolicy(s string) (throttle.ErrorPolicy, error) {
+	switch s {
+	case "", "continue":
+		return throttle.ContinueOnError, nil
+	case "abort":
+		return throttle.AbortOnError, nil
+	}
+	return 0, fmt.Errorf("incorrect value to on_error option: %s: must be continue or abort", s)
+}
+
+// parseDedupeMode parses the dedupe option.
+func parseDedupeMode(s string) (throttle.DedupeMode, error) {
+	switch s {
+	case "", "off":
+		return throttle.DedupeOff, nil
+	case "exact":
+		return throttle.DedupeExact, nil
+	case "normalize":
+		return throttle.DedupeNormalized, nil
+	}
+	return 0, fmt.Errorf("incorrect value to dedupe option: %s: must be off, exact or normalize", s)
+}
+
+// parseTextFormat parses the format option.
+func parseTextFormat(s string) (slack.TextFormat, error) {
+	switch s {
+	case "", "raw":
+		return slack.FormatRaw, nil
+	case "escape":
+		return slack.FormatEscape, nil
+	case "code", "blocks":
+		// Output in blocks is shown in a code block too
+		return slack.FormatCode, nil
+	}
+	return 0, fmt.Errorf("incorrect value to format option: %s: must be raw, escape, code or blocks", s)
+}
+
+// stripANSI reports whether escape sequences and overwritten text are
+// removed from the posted output according to the ansi option.
+func (c *CLI) stripANSI(mode string) (bool, error) {
+	switch mode {
+	case "", "auto":
+		return !c.isStdinTerminal, nil
+	case "strip":
+		return true, nil
+	case "keep":
+		return false, nil
+	}
+	return false, fmt.Errorf("incorrect value to ansi option: %s: must be auto, strip or keep", mode)
 }
 
 func (c *CLI) uploadSnippet(ctx context.Context, filename, uploadFilename, snippetType string) error {
@@ -285,6 +1102,10 @@ func (c *CLI) uploadSnippet(ctx context.Context, filename, uploadFilename, snipp
 		return fmt.Errorf("input is %d bytes; snippet uploads are capped at %d bytes", len(content), maxSnippetBytes)
 	}
 
+	if c.redactor != nil {
+		content = c.redactor.Redact(content)
+	}
+
 	if uploadFilename == "" {
 		uploadFilename = filename
 	}
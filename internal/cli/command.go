@@ -0,0 +1,170 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+)
+
+// forwardedSignals are relayed to the wrapped command instead of stopping
+// notify_slack, so that the command decides how to shut down and its
+// remaining output still reaches Slack.
+var forwardedSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT}
+
+// keyboardSignals are sent by the terminal to its whole foreground process
+// group, which the command is part of too.
+var keyboardSignals = []os.Signal{syscall.SIGINT, syscall.SIGQUIT}
+
+// childCommand runs a command and merges its stdout and stderr into a
+// single stream of lines.
+type childCommand struct {
+	cmd *exec.Cmd
+
+	// output yields the merged lines of stdout and stderr. It is closed
+	// after the command exits.
+	output *io.PipeReader
+
+	signals chan os.Signal
+	done    chan struct{}
+	err     error // the result of cmd.Wait, set before done is closed
+}
+
+// startCommand starts args[0] with the rest of args as its arguments.
+// stdout and stderr of the command are copied to stdout and stderr as is,
+// and stderr lines are labeled with stderrPrefix in the merged output.
+func startCommand(args []string, stdin io.Reader, stdout, stderr io.Writer, stderrPrefix string) (*childCommand, error) {
+	cmd := exec.Command(args[0], args[1:]...)
+	cmd.Stdin = stdin
+
+	outPipe, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	errPipe, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	c := &childCommand{
+		cmd:     cmd,
+		signals: make(chan os.Signal, 1),
+		done:    make(chan struct{}),
+	}
+
+	// Catch signals before the command starts so that none of them
+	// terminates notify_slack in between
+	signal.Notify(c.signals, forwardedSignals...)
+
+	if err := cmd.Start(); err != nil {
+		signal.Stop(c.signals)
+		return nil, err
+	}
+
+	go c.forwardSignals()
+
+	pr, pw := io.Pipe()
+	c.output = pr
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		copyLines(pw, &mu, io.TeeReader(outPipe, stdout), "")
+	})
+	wg.Go(func() {
+		copyLines(pw, &mu, io.TeeReader(errPipe, stderr), stderrPrefix)
+	})
+
+	go func() {
+		// All output must be read before calling Wait
+		wg.Wait()
+		c.err = cmd.Wait()
+
+		signal.Stop(c.signals)
+		close(c.signals)
+
+		pw.Close()
+		close(c.done)
+	}()
+
+	return c, nil
+}
+
+// forwardSignals relays the signals received by notify_slack to the command.
+func (c *childCommand) forwardSignals() {
+	for sig := range c.signals {
+		if !shouldForward(sig, inForeground()) {
+			continue
+		}
+		// The command may have exited in the meantime
+		_ = c.cmd.Process.Signal(sig)
+	}
+}
+
+// shouldForward reports whether sig is relayed to the command. A signal
+// typed on the terminal, such as Ctrl-C, has already reached the command
+// when notify_slack is in the foreground. Sending it again would look like
+// a second interrupt, which tools like terraform take as a request to exit
+// immediately.
+func shouldForward(sig os.Signal, foreground bool) bool {
+	return !foreground || !slices.Contains(keyboardSignals, sig)
+}
+
+// wait waits for the command to exit and returns its exit code.
+// A command killed by a signal is reported as 128 + the signal number like
+// shells do.
+func (c *childCommand) wait() int {
+	<-c.done
+
+	if c.err == nil {
+		return ExitCodeOK
+	}
+
+	var exitErr *exec.ExitError
+	if !errors.As(c.err, &exitErr) {
+		return ExitCodeFail
+	}
+
+	if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		return 128 + int(status.Signal())
+	}
+
+	return exitErr.ExitCode()
+}
+
+// copyLines copies lines from r to w with prefix. Each line is written in
+// a single call while holding mu so that lines from stdout and stderr are
+// never interleaved.
+func copyLines(w io.Writer, mu *sync.Mutex, r io.Reader, prefix string) {
+	reader := bufio.NewReader(r)
+
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			if line[len(line)-1] != '\n' {
+				line = append(line, '\n')
+			}
+			if prefix != "" {
+				line = append([]byte(prefix), line...)
+			}
+
+			mu.Lock()
+			_, werr := w.Write(line)
+			mu.Unlock()
+			if werr != nil {
+				// Nobody reads the output anymore. Keep draining the
+				// pipe so that the command doesn't block on writing.
+				io.Copy(io.Discard, reader)
+				return
+			}
+		}
+		if err != nil {
+			return
+		}
+	}
+}
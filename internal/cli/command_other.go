@@ -0,0 +1,9 @@
+//go:build !unix
+
+package cli
+
+// inForeground reports whether notify_slack is in the foreground process
+// group of its terminal. Process groups are only known on Unix.
+func inForeground() bool {
+	return false
+}
@@ -0,0 +1,28 @@
+package cli
+
+import (
+	"syscall"
+	"testing"
+)
+
+func TestShouldForward(t *testing.T) {
+	tests := []struct {
+		sig        syscall.Signal
+		foreground bool
+		want       bool
+	}{
+		{sig: syscall.SIGINT, foreground: false, want: true},
+		{sig: syscall.SIGTERM, foreground: false, want: true},
+		// The terminal sent it to the command too
+		{sig: syscall.SIGINT, foreground: true, want: false},
+		{sig: syscall.SIGQUIT, foreground: true, want: false},
+		{sig: syscall.SIGTERM, foreground: true, want: true},
+		{sig: syscall.SIGHUP, foreground: true, want: true},
+	}
+
+	for _, tt := range tests {
+		if got := shouldForward(tt.sig, tt.foreground); got != tt.want {
+			t.Errorf("shouldForward(%s, %t) = %t, want %t", tt.sig, tt.foreground, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,44 @@
+//go:build unix
+
+package cli
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestStartCommand_forwardSignals(t *testing.T) {
+	script := `trap 'echo got TERM; exit 7' TERM; echo ready; while :; do sleep 0.05; done`
+	child, err := startCommand([]string{"sh", "-c", script}, strings.NewReader(""), io.Discard, io.Discard, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := bufio.NewReader(child.output)
+	line, err := output.ReadString('\n')
+	if err != nil {
+		t.Fatal(err)
+	}
+	if line != "ready\n" {
+		t.Fatalf("got %q, want %q", line, "ready\n")
+	}
+
+	// notify_slack catches the signal and relays it to the command
+	if err := syscall.Kill(syscall.Getpid(), syscall.SIGTERM); err != nil {
+		t.Fatal(err)
+	}
+
+	rest, err := io.ReadAll(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(rest) != "got TERM\n" {
+		t.Errorf("got %q, want %q", rest, "got TERM\n")
+	}
+	if status := child.wait(); status != 7 {
+		t.Errorf("ExitStatus=%d, want %d", status, 7)
+	}
+}
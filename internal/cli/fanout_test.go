@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/catatsuy/notify_slack/internal/config"
+	"github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseFlags_slackURLs(t *testing.T) {
+	cl := NewCLI(new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer), true)
+
+	args := strings.Split("notify_slack -slack-url https://hooks.slack.com/aaaaa -slack-url https://hooks.slack.com/bbbbb -slack-url https://hooks.slack.com/ccccc", " ")
+	if _, err := cl.parseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"https://hooks.slack.com/aaaaa", "https://hooks.slack.com/bbbbb", "https://hooks.slack.com/ccccc"}
+	if diff := cmp.Diff(expected, cl.slackURLs()); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+
+	fanOut, err := cl.newFanOut(cl.slackURLs(), cl.fanOutChannels(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fanOut.Destinations); n != 3 {
+		t.Fatalf("got %d, want %d", n, 3)
+	}
+	if name := fanOut.Destinations[2].Name; name != "slack url #3" {
+		t.Errorf("got %s, want %s", name, "slack url #3")
+	}
+}
+
+func TestParseFlags_channels(t *testing.T) {
+	cl := NewCLI(new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer), true)
+
+	args := strings.Split("notify_slack -token xoxb-token -channel #team -channel #ops -channel #audit", " ")
+	if _, err := cl.parseFlags(args); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without URLs, the main channel is posted to with the token as well
+	expected := []string{"#team", "#ops", "#audit"}
+	if diff := cmp.Diff(expected, cl.fanOutChannels()); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+
+	fanOut, err := cl.newFanOut(cl.slackURLs(), cl.fanOutChannels(), slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fanOut.Destinations); n != 3 {
+		t.Fatalf("got %d, want %d", n, 3)
+	}
+	if d := fanOut.Destinations[1]; d.Name != "#ops" || d.Channel != "#ops" {
+		t.Errorf("got %s and %s, want %s", d.Name, d.Channel, "#ops")
+	}
+
+	// With a URL, the main channel goes with the URL
+	cl.conf.SlackURL = "https://hooks.slack.com/aaaaa"
+	expected = []string{"#ops", "#audit"}
+	if diff := cmp.Diff(expected, cl.fanOutChannels()); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+
+	cl.conf.Token = ""
+	if _, err := cl.newFanOut(cl.slackURLs(), cl.fanOutChannels(), slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Error("expected an error without a token")
+	}
+}
+
+func TestRun_severalDestinations(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	dir := t.TempDir()
+	tomlFile := filepath.Join(dir, "notify_slack.toml")
+	toml := fmt.Sprintf("[[follow]]\npath = %q\nchannel = \"#db\"\n", filepath.Join(dir, "db.log"))
+	if err := os.WriteFile(tomlFile, []byte(toml), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		args     string
+		expected string
+	}{
+		{"-token xoxb-token -channel #a -channel #b -thread", "cannot use update mode and thread mode with several"},
+		{"-token xoxb-token -channel #a -channel #b -update", "cannot use update mode and thread mode with several"},
+		{"-via unix:///run/notify_slack.sock -slack-url https://hooks.slack.com/aaaaa -slack-url https://hooks.slack.com/bbbbb", "cannot use -via with several"},
+		{"-c " + tomlFile + " -token xoxb-token -channel #a -channel #b", "cannot post followed files to their own channels with several"},
+	}
+	for _, tt := range tests {
+		errStream := new(bytes.Buffer)
+		cl := NewCLI(new(bytes.Buffer), errStream, strings.NewReader("abc\n"), false)
+
+		status := cl.Run(append([]string{"notify_slack"}, strings.Split(tt.args, " ")...))
+		if status != ExitCodeFail {
+			t.Errorf("%s: ExitStatus=%d, want %d", tt.args, status, ExitCodeFail)
+		}
+		if !strings.Contains(errStream.String(), tt.expected) {
+			t.Errorf("expected %q to contain %q", errStream.String(), tt.expected)
+		}
+	}
+}
+
+func TestStreamToSlack_fanOut(t *testing.T) {
+	var mu sync.Mutex
+	posted := map[string][]string{}
+
+	newDestination := func(name string, fail bool) slack.Destination {
+		return slack.Destination{
+			Name: name,
+			Slack: &fakeSlackClient{
+				FakePostText: func(ctx context.Context, param *slack.PostTextParam) error {
+					if fail {
+						return fmt.Errorf("status code: 500")
+					}
+					mu.Lock()
+					defer mu.Unlock()
+					posted[name] = append(posted[name], param.Text)
+					return nil
+				},
+			},
+		}
+	}
+
+	// A slow destination doesn't hold back the others
+	release := make(chan struct{})
+	slow := slack.Destination{
+		Name: "#slow",
+		Slack: &fakeSlackClient{
+			FakePostText: func(ctx context.Context, param *slack.PostTextParam) error {
+				<-release
+				mu.Lock()
+				defer mu.Unlock()
+				posted["#slow"] = append(posted["#slow"], param.Text)
+				return nil
+			},
+		},
+	}
+
+	errStream := new(bytes.Buffer)
+	cl := &CLI{
+		outStream:   new(bytes.Buffer),
+		errStream:   errStream,
+		inputStream: strings.NewReader("abc\n"),
+		conf:        config.NewConfig(),
+	}
+	cl.conf.Duration = time.Hour
+	cl.conf.MessageLimit = slack.DefaultTextLimit
+
+	fanOut := slack.NewFanOut([]slack.Destination{
+		newDestination("slack url #1", false),
+		newDestination("slack url #2", true),
+		slow,
+		newDestination("slack url #3", false),
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	done := make(chan int)
+	go func() {
+		done <- cl.streamToFanOut(t.Context(), &cliOptions{}, fanOut)
+	}()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(posted["slack url #3"])
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the other destinations")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	close(release)
+
+	status := <-done
+	if status != ExitCodeFail {
+		t.Errorf("ExitStatus=%d, want %d", status, ExitCodeFail)
+	}
+
+	// The failing destination doesn't keep the others from getting the text
+	expected := map[string][]string{
+		"slack url #1": {"abc\n"},
+		"#slow":        {"abc\n"},
+		"slack url #3": {"abc\n"},
+	}
+	if diff := cmp.Diff(expected, posted); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+
+	expectedErr := "slack url #2: 1 of 1 texts failed: status code: 500"
+	if !strings.Contains(errStream.String(), expectedErr) {
+		t.Errorf("expected %q to contain %q", errStream.String(), expectedErr)
+	}
+}
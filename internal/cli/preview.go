@@ -0,0 +1,62 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/catatsuy/notify_slack/internal/slack"
+)
+
+// defaultSnippetPreviewLines is the number of lines shown from the start
+// and from the end of output uploaded as a snippet unless configured
+// otherwise.
+const defaultSnippetPreviewLines = 5
+
+// snippetPreview returns the first and last n lines of output, which has
+// lines lines, with a line telling how many lines are omitted in between.
+// The lines are formatted with format and cut to fit in limit characters.
+func snippetPreview(output string, lines, n, limit int, format slack.TextFormat) string {
+	header := fmt.Sprintf(":page_facing_up: %d lines; showing the first and last %d, the full output is attached\n", lines, n)
+
+	var b strings.Builder
+	i := 0
+	for line := range strings.Lines(output) {
+		switch {
+		case i < n || i >= lines-n:
+			b.WriteString(line)
+		case i == n:
+			fmt.Fprintf(&b, "… %d lines omitted …\n", lines-2*n)
+		}
+		i++
+	}
+
+	// Keep the comment within the limits of a message even with long lines
+	if limit > 0 {
+		limit = max(limit-utf8.RuneCountInString(header), 1)
+	}
+	return header + format.Split(b.String(), limit)[0]
+}
+
+// postSnippet uploads output as a snippet with a preview of its first and
+// last lines as the comment, so that a huge flush doesn't flood the
+// channel. The mention, if any, is put before the preview.
+func (c *CLI) postSnippet(ctx context.Context, output string, lines int, mention string, format slack.TextFormat) error {
+	n := c.conf.SnippetPreviewLines
+	if n <= 0 {
+		n = defaultSnippetPreviewLines
+	}
+
+	preview := snippetPreview(output, lines, n, c.conf.MessageLimit, format)
+
+	param := &slack.PostFileParam{
+		ChannelID:      c.conf.ChannelID,
+		Filename:       fmt.Sprintf("output-%s.log", time.Now().Format("20060102-150405")),
+		SnippetType:    "text",
+		InitialComment: alert{mention: mention, text: preview}.message(),
+	}
+
+	return c.sClient.PostFile(ctx, param, []byte(output))
+}
@@ -0,0 +1,88 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/catatsuy/notify_slack/internal/config"
+	"github.com/catatsuy/notify_slack/internal/slack"
+)
+
+func TestSnippetPreview(t *testing.T) {
+	var b strings.Builder
+	for i := 1; i <= 10; i++ {
+		fmt.Fprintf(&b, "line %d\n", i)
+	}
+
+	expected := ":page_facing_up: 10 lines; showing the first and last 2, the full output is attached\n" +
+		"line 1\nline 2\n… 6 lines omitted …\nline 9\nline 10\n"
+	if got := snippetPreview(b.String(), 10, 2, slack.DefaultTextLimit, slack.FormatRaw); got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+
+	expected = ":page_facing_up: 10 lines; showing the first and last 1, the full output is attached\n" +
+		"```\nline 1\n… 8 lines omitted …\nline 10\n```"
+	if got := snippetPreview(b.String(), 10, 1, slack.DefaultTextLimit, slack.FormatCode); got != expected {
+		t.Errorf("got %q, want %q", got, expected)
+	}
+}
+
+func TestStreamToSlack_snippetLines(t *testing.T) {
+	var input strings.Builder
+	for i := 1; i <= 20; i++ {
+		fmt.Fprintf(&input, "line %d\n", i)
+	}
+
+	var texts []string
+	var files []slack.PostFileParam
+	var contents []string
+	cl := &CLI{
+		outStream:   new(bytes.Buffer),
+		inputStream: strings.NewReader(input.String()),
+		sClient: &fakeSlackClient{
+			FakePostText: func(ctx context.Context, param *slack.PostTextParam) error {
+				texts = append(texts, param.Text)
+				return nil
+			},
+			FakePostFile: func(ctx context.Context, param *slack.PostFileParam, content []byte) error {
+				files = append(files, *param)
+				contents = append(contents, string(content))
+				return nil
+			},
+		},
+		conf: config.NewConfig(),
+	}
+	cl.conf.Duration = time.Hour
+	cl.conf.MessageLimit = slack.DefaultTextLimit
+	cl.conf.ChannelID = "C12345678"
+	cl.conf.SnippetLines = 10
+	cl.conf.SnippetPreviewLines = 1
+
+	status := cl.streamToSlack(t.Context(), &cliOptions{})
+	if status != ExitCodeOK {
+		t.Errorf("ExitStatus=%d, want %d", status, ExitCodeOK)
+	}
+
+	if len(texts) != 0 {
+		t.Errorf("expected no text messages, got %q", texts)
+	}
+
+	if len(files) != 1 {
+		t.Fatalf("expected 1 file, got %d", len(files))
+	}
+	if files[0].ChannelID != "C12345678" {
+		t.Errorf("got %s, want %s", files[0].ChannelID, "C12345678")
+	}
+	expectedComment := ":page_facing_up: 20 lines; showing the first and last 1, the full output is attached\n" +
+		"line 1\n… 18 lines omitted …\nline 20\n"
+	if files[0].InitialComment != expectedComment {
+		t.Errorf("got %q, want %q", files[0].InitialComment, expectedComment)
+	}
+	if contents[0] != input.String() {
+		t.Errorf("got %q, want %q", contents[0], input.String())
+	}
+}
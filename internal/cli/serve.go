@@ -0,0 +1,338 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/catatsuy/notify_slack/internal/config"
+	"github.com/catatsuy/notify_slack/internal/redact"
+	"github.com/catatsuy/notify_slack/internal/relay"
+	"github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/catatsuy/notify_slack/internal/syslog"
+)
+
+// serveShutdownTimeout is how long the relay waits for requests in flight
+// when it is stopped.
+const serveShutdownTimeout = 10 * time.Second
+
+type serveOptions struct {
+	tomlFile  string
+	debugMode bool
+
+	socket          string
+	http            string
+	httpAllowRemote bool
+	rate            time.Duration
+}
+
+// runServe runs the relay daemon until SIGINT or SIGTERM. args[0] is
+// "serve".
+func (c *CLI) runServe(args []string) int {
+	opts := &serveOptions{}
+	c.conf = config.NewConfig()
+
+	flags := flag.NewFlagSet("notify_slack serve", flag.ContinueOnError)
+	flags.SetOutput(c.errStream)
+
+	flags.StringVar(&c.conf.Channel, "channel", "", "default channel of the messages (unavailable for new Incoming Webhooks)")
+	flags.StringVar(&c.conf.ChannelID, "channel-id", "", "default channel id of the files")
+	flags.Var(&slackURLsFlag{conf: c.conf}, "slack-url", "slack url (Incoming Webhooks URL); can be repeated to post the same text to each of them")
+	flags.StringVar(&c.conf.Token, "token", "", "token (for uploading files, or for posting with chat.postMessage)")
+	flags.StringVar(&c.conf.Username, "username", "", "default username of the messages (unavailable for new Incoming Webhooks)")
+	flags.StringVar(&c.conf.IconEmoji, "icon-emoji", "", "default icon emoji of the messages (unavailable for new Incoming Webhooks)")
+	flags.DurationVar(&c.conf.Duration, "interval", time.Second, "how often the text sent to each destination is posted")
+	flags.IntVar(&c.conf.MessageLimit, "message-limit", 0, fmt.Sprintf("maximum number of characters per message (default %d)", slack.DefaultTextLimit))
+	flags.IntVar(&c.conf.RetryMaxAttempts, "retry-max-attempts", 0, fmt.Sprintf("maximum number of attempts for a request rate limited or failed by Slack (default %d)", slack.DefaultRetryMaxAttempts))
+	flags.DurationVar(&c.conf.RetryMaxElapsed, "retry-max-elapsed", 0, fmt.Sprintf("give up retrying a request after this duration (default %s)", slack.DefaultRetryMaxElapsed))
+	flags.StringVar(&opts.tomlFile, "c", "", "config file name")
+	flags.StringVar(&opts.socket, "socket", "", "listen on this Unix socket")
+	flags.StringVar(&opts.http, "http", "", "listen for HTTP on this address, e.g. 127.0.0.1:8125 (only loopback addresses unless -http-allow-remote is set)")
+	flags.BoolVar(&opts.httpAllowRemote, "http-allow-remote", false, "allow -http to listen on an address reachable from other hosts; the relay has no authentication")
+	flags.DurationVar(&opts.rate, "rate", relay.DefaultRateInterval, "minimum interval between two requests to Slack")
+	flags.StringVar(&c.conf.SyslogListen, "syslog", "", "receive syslog messages on this address, e.g. udp://127.0.0.1:5514")
+	flags.StringVar(&c.conf.SyslogSeverity, "syslog-severity", "", "least severe syslog messages to post: emerg, alert, crit, err, warning, notice, info or debug (default debug)")
+	flags.StringVar(&c.conf.SyslogChannel, "syslog-channel", "", "channel of the syslog messages instead of -channel")
+	flags.BoolVar(&opts.debugMode, "debug", false, "debug mode (for developers)")
+
+	if err := flags.Parse(args[1:]); err != nil {
+		return ExitCodeParseFlagError
+	}
+	if flags.NArg() > 0 {
+		fmt.Fprintln(c.errStream, "serve doesn't take any arguments")
+		return ExitCodeParseFlagError
+	}
+
+	if err := c.loadConfiguration(opts.tomlFile); err != nil {
+		fmt.Fprintln(c.errStream, err)
+		return ExitCodeFail
+	}
+
+	// The syslog address may be given in the config file
+	if opts.socket == "" && opts.http == "" && c.conf.SyslogListen == "" {
+		fmt.Fprintln(c.errStream, "must specify -socket, -http or -syslog to serve")
+		return ExitCodeParseFlagError
+	}
+	if opts.http != "" && !opts.httpAllowRemote {
+		if err := checkLoopback(opts.http); err != nil {
+			fmt.Fprintln(c.errStream, err)
+			return ExitCodeParseFlagError
+		}
+	}
+
+	syslogInput, err := c.syslogInput()
+	if err != nil {
+		fmt.Fprintln(c.errStream, err)
+		return ExitCodeFail
+	}
+
+	logger := c.createLogger(opts.debugMode)
+
+	server, err := c.newRelayServer(opts, logger)
+	if err != nil {
+		fmt.Fprintln(c.errStream, err)
+		return ExitCodeFail
+	}
+
+	listeners, err := listenRelay(opts, c.conf.SyslogListen)
+	if err != nil {
+		fmt.Fprintln(c.errStream, err)
+		return ExitCodeFail
+	}
+	listeners.syslogInput = syslogInput
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	return c.serveRelay(ctx, server, listeners, logger)
+}
+
+func (c *CLI) newRelayServer(opts *serveOptions, logger *slog.Logger) (*relay.Server, error) {
+	var sClient slack.Slack
+	var err error
+	urls := c.slackURLs()
+	if len(urls) > 1 {
+		// The jobs choose the channel, so only the URLs are fanned out
+		fanOut, err := c.newFanOut(urls, nil, logger)
+		if err != nil {
+			return nil, err
+		}
+		sClient = fanOut
+	} else {
+		var client *slack.Client
+		if len(urls) == 1 {
+			client, err = slack.NewClient(urls[0], logger)
+			if err == nil {
+				// Files are uploaded with the token
+				client.Token = c.conf.Token
+			}
+		} else if c.conf.Token != "" {
+			client, err = slack.NewClientForPostFile(c.conf.Token, logger)
+		} else {
+			return nil, errors.New("must specify Slack URL or Slack token to serve")
+		}
+		if err != nil {
+			return nil, err
+		}
+		client.Retry = c.retryPolicy()
+		sClient = client
+	}
+
+	server := relay.NewServer(sClient, opts.rate, logger)
+	server.Channel = c.conf.Channel
+	if len(urls) == 0 {
+		// chat.postMessage accepts a channel ID as well as a channel name
+		server.Channel = c.postChannel()
+	}
+	server.ChannelID = c.conf.ChannelID
+	server.Username = c.conf.Username
+	server.IconEmoji = c.conf.IconEmoji
+	server.Interval = c.conf.Duration
+	if c.conf.MessageLimit > 0 {
+		server.MessageLimit = c.conf.MessageLimit
+	}
+
+	server.Redactor, err = redact.New(c.conf.RedactPatterns, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return server, nil
+}
+
+func (c *CLI) syslogInput() (relay.SyslogInput, error) {
+	input := relay.SyslogInput{
+		Channel:     c.conf.SyslogChannel,
+		MinSeverity: syslog.Debug,
+	}
+	if c.conf.SyslogSeverity != "" {
+		severity, err := syslog.ParseSeverity(c.conf.SyslogSeverity)
+		if err != nil {
+			return input, err
+		}
+		input.MinSeverity = severity
+	}
+	return input, nil
+}
+
+// checkLoopback returns an error unless addr only accepts connections from
+// the same host. Anyone who can reach the relay can post with its
+// credentials.
+func checkLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("incorrect value to http option: %s: %w", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("incorrect value to http option: %s: must be a loopback address such as 127.0.0.1:8125, or set -http-allow-remote", addr)
+}
+
+// relayListeners are the sockets the relay serves on.
+type relayListeners struct {
+	// http serve the relay API
+	http []net.Listener
+
+	syslog      net.PacketConn
+	syslogInput relay.SyslogInput
+}
+
+func (l *relayListeners) close() {
+	for _, hl := range l.http {
+		hl.Close()
+	}
+	if l.syslog != nil {
+		l.syslog.Close()
+	}
+}
+
+// listenRelay opens the Unix socket and the HTTP address to serve on, and
+// the syslog address if set.
+func listenRelay(opts *serveOptions, syslogAddr string) (*relayListeners, error) {
+	listeners := &relayListeners{}
+
+	if opts.socket != "" {
+		// A socket left behind by a previous run would make Listen fail
+		if info, err := os.Lstat(opts.socket); err == nil && info.Mode().Type() == fs.ModeSocket {
+			os.Remove(opts.socket)
+		}
+		l, err := net.Listen("unix", opts.socket)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", opts.socket, err)
+		}
+		listeners.http = append(listeners.http, l)
+	}
+
+	if opts.http != "" {
+		l, err := net.Listen("tcp", opts.http)
+		if err != nil {
+			listeners.close()
+			return nil, fmt.Errorf("failed to listen on %s: %w", opts.http, err)
+		}
+		listeners.http = append(listeners.http, l)
+	}
+
+	if syslogAddr != "" {
+		conn, err := listenSyslog(syslogAddr)
+		if err != nil {
+			listeners.close()
+			return nil, err
+		}
+		listeners.syslog = conn
+	}
+
+	return listeners, nil
+}
+
+// listenSyslog opens addr, which must be like udp://127.0.0.1:5514.
+func listenSyslog(addr string) (net.PacketConn, error) {
+	u, err := url.Parse(addr)
+	if err != nil || u.Scheme != "udp" || u.Host == "" {
+		return nil, fmt.Errorf("incorrect value to syslog option: %s: must be like udp://127.0.0.1:5514", addr)
+	}
+	conn, err := net.ListenPacket("udp", u.Host)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return conn, nil
+}
+
+// serveRelay serves on listeners until ctx is done, and then posts the
+// pending text before returning.
+func (c *CLI) serveRelay(ctx context.Context, server *relay.Server, listeners *relayListeners, logger *slog.Logger) int {
+	httpServer := &http.Server{
+		Handler:           server.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(listeners.http)+1)
+	for _, l := range listeners.http {
+		logger.Info("serving", slog.String("addr", l.Addr().String()))
+		wg.Go(func() {
+			if err := httpServer.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				errs <- err
+			}
+		})
+	}
+
+	var syslogWG sync.WaitGroup
+	if listeners.syslog != nil {
+		logger.Info("receiving syslog", slog.String("addr", listeners.syslog.LocalAddr().String()))
+		syslogWG.Go(func() {
+			if err := server.ServeSyslog(listeners.syslog, listeners.syslogInput); err != nil {
+				errs <- err
+			}
+		})
+	}
+
+	exitCode := ExitCodeOK
+	select {
+	case <-ctx.Done():
+	case err := <-errs:
+		fmt.Fprintln(c.errStream, err)
+		exitCode = ExitCodeFail
+	}
+
+	if listeners.syslog != nil {
+		listeners.syslog.Close()
+		syslogWG.Wait()
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), serveShutdownTimeout)
+	defer cancel()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		fmt.Fprintln(c.errStream, err)
+		exitCode = ExitCodeFail
+	}
+	wg.Wait()
+
+	if err := server.Close(); err != nil {
+		fmt.Fprintln(c.errStream, err)
+		exitCode = ExitCodeFail
+	}
+	// Wait for the destinations still posting
+	if fanOut, ok := server.Slack.(*slack.FanOut); ok {
+		if err := fanOut.Close(); err != nil {
+			fmt.Fprintln(c.errStream, err)
+			exitCode = ExitCodeFail
+		}
+	}
+
+	return exitCode
+}
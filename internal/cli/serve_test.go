@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/catatsuy/notify_slack/internal/relay"
+	"github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRun_serveWithoutListener(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	errStream := new(bytes.Buffer)
+	cl := NewCLI(new(bytes.Buffer), errStream, new(bytes.Buffer), true)
+
+	status := cl.Run([]string{"notify_slack", "serve", "-slack-url", "https://hooks.slack.com/aaaaa"})
+	if status != ExitCodeParseFlagError {
+		t.Errorf("ExitStatus=%d, want %d", status, ExitCodeParseFlagError)
+	}
+
+	expected := "must specify -socket, -http or -syslog"
+	if !strings.Contains(errStream.String(), expected) {
+		t.Errorf("expected %q to contain %q", errStream.String(), expected)
+	}
+}
+
+func TestRun_serveRemoteHTTP(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	errStream := new(bytes.Buffer)
+	cl := NewCLI(new(bytes.Buffer), errStream, new(bytes.Buffer), true)
+
+	status := cl.Run([]string{"notify_slack", "serve", "-slack-url", "https://hooks.slack.com/aaaaa", "-http", ":8125"})
+	if status != ExitCodeParseFlagError {
+		t.Errorf("ExitStatus=%d, want %d", status, ExitCodeParseFlagError)
+	}
+
+	expected := "must be a loopback address"
+	if !strings.Contains(errStream.String(), expected) {
+		t.Errorf("expected %q to contain %q", errStream.String(), expected)
+	}
+}
+
+func TestCheckLoopback(t *testing.T) {
+	tests := []struct {
+		addr string
+		ok   bool
+	}{
+		{"127.0.0.1:8125", true},
+		{"[::1]:8125", true},
+		{"localhost:8125", true},
+		{":8125", false},
+		{"0.0.0.0:8125", false},
+		{"192.0.2.1:8125", false},
+		{"relay.example.com:8125", false},
+		{"127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		err := checkLoopback(tt.addr)
+		if (err == nil) != tt.ok {
+			t.Errorf("checkLoopback(%q) = %v", tt.addr, err)
+		}
+	}
+}
+
+func TestRun_via(t *testing.T) {
+	t.Setenv("HOME", t.TempDir())
+
+	var mu sync.Mutex
+	var texts []string
+	fake := &fakeSlackClient{
+		FakePostText: func(ctx context.Context, param *slack.PostTextParam) error {
+			mu.Lock()
+			defer mu.Unlock()
+			texts = append(texts, param.Channel+": "+param.Text)
+			return nil
+		},
+	}
+
+	server := relay.NewServer(fake, 0, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	server.Channel = "#default"
+
+	socket := filepath.Join(t.TempDir(), "notify_slack.sock")
+	listeners, err := listenRelay(&serveOptions{socket: socket}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(t.Context())
+	serverCL := NewCLI(new(bytes.Buffer), new(bytes.Buffer), new(bytes.Buffer), true)
+	done := make(chan int)
+	go func() {
+		done <- serverCL.serveRelay(ctx, server, listeners, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	}()
+
+	for _, input := range []string{"first job\n", "second job\n"} {
+		errStream := new(bytes.Buffer)
+		cl := NewCLI(new(bytes.Buffer), errStream, strings.NewReader(input), false)
+		status := cl.Run([]string{"notify_slack", "-via", "unix://" + socket})
+		if status != ExitCodeOK {
+			t.Fatalf("ExitStatus=%d, want %d: %s", status, ExitCodeOK, errStream.String())
+		}
+	}
+
+	cancel()
+	if status := <-done; status != ExitCodeOK {
+		t.Errorf("ExitStatus=%d, want %d", status, ExitCodeOK)
+	}
+
+	// Both jobs are merged into one message when the server stops
+	expected := []string{"#default: first job\nsecond job\n"}
+	if diff := cmp.Diff(expected, texts); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+}
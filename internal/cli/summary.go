@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
+
+	"github.com/catatsuy/notify_slack/internal/slack"
+)
+
+// outputCounter counts the lines and bytes written to it. It is used with
+// io.TeeReader to measure the input.
+type outputCounter struct {
+	lines atomic.Int64
+	bytes atomic.Int64
+}
+
+func (c *outputCounter) Write(p []byte) (int, error) {
+	c.bytes.Add(int64(len(p)))
+	c.lines.Add(int64(bytes.Count(p, []byte{'\n'})))
+	return len(p), nil
+}
+
+// jobSummary describes a finished job for the completion summary message.
+type jobSummary struct {
+	title   string
+	command string
+	host    string
+	dir     string
+
+	start time.Time
+	end   time.Time
+
+	lines int64
+	bytes int64
+
+	// exitCode is only meaningful when exitKnown is set, that is when
+	// notify_slack ran the command itself.
+	exitCode  int
+	exitKnown bool
+}
+
+// newJobSummary returns a summary of a job started at start. command is
+// the command line run after --, with secrets redacted, if any.
+func newJobSummary(title, command string, start time.Time) *jobSummary {
+	s := &jobSummary{
+		title:   title,
+		command: command,
+		start:   start,
+	}
+	// The summary is still useful without these
+	s.host, _ = os.Hostname()
+	s.dir, _ = os.Getwd()
+
+	return s
+}
+
+func (s *jobSummary) failed() bool {
+	return s.exitKnown && s.exitCode != 0
+}
+
+// param renders the summary as Block Kit with a green or red bar.
+// Text is the fallback shown in notifications.
+func (s *jobSummary) param(base slack.PostTextParam) *slack.PostTextParam {
+	title := s.title
+	if title == "" {
+		title = "Job finished"
+		if s.command != "" {
+			title = "Command finished"
+		}
+	}
+
+	status := "done"
+	color := slack.ColorGood
+	if s.exitKnown {
+		status = fmt.Sprintf("exit status %d", s.exitCode)
+	}
+	if s.failed() {
+		color = slack.ColorDanger
+	}
+
+	duration := s.end.Sub(s.start).Round(time.Millisecond)
+
+	var fields []*slack.TextObject
+	if s.command != "" {
+		fields = append(fields, summaryField("Command", s.command, inlineCode))
+	}
+	if s.exitKnown {
+		fields = append(fields, slack.NewMrkdwn(fmt.Sprintf("*Exit status*\n%d", s.exitCode)))
+	}
+	if s.host != "" {
+		fields = append(fields, summaryField("Host", s.host, slack.EscapeText))
+	}
+	if s.dir != "" {
+		fields = append(fields, summaryField("Directory", s.dir, slack.EscapeText))
+	}
+	fields = append(fields,
+		slack.NewMrkdwn(fmt.Sprintf("*Started*\n%s", slackDate(s.start))),
+		slack.NewMrkdwn(fmt.Sprintf("*Finished*\n%s", slackDate(s.end))),
+		slack.NewMrkdwn(fmt.Sprintf("*Duration*\n%s", duration)),
+		slack.NewMrkdwn(fmt.Sprintf("*Output*\n%d lines, %d bytes", s.lines, s.bytes)),
+	)
+
+	param := base
+	param.Text = fmt.Sprintf("%s (%s) in %s", title, status, duration)
+	param.Attachments = []slack.Attachment{
+		{
+			Color:    color,
+			Fallback: param.Text,
+			Blocks: []slack.Block{
+				slack.NewHeaderBlock(truncateText(title, slack.MaxHeaderTextLength)),
+				slack.NewSectionBlock(nil, fields...),
+			},
+		},
+	}
+
+	return &param
+}
+
+// summaryField returns a field showing value formatted with format under
+// name. The value is cut so that the field fits in the limit of Slack.
+func summaryField(name, value string, format func(string) string) *slack.TextObject {
+	label := "*" + name + "*\n"
+	limit := slack.MaxFieldTextLength - utf8.RuneCountInString(label)
+
+	n := utf8.RuneCountInString(value)
+	text := format(value)
+	// Escaping makes the text longer than the value, so the value is cut
+	// by the ratio of their lengths until the text fits
+	for length := utf8.RuneCountInString(text); length > limit && n > 1; length = utf8.RuneCountInString(text) {
+		n = max(min(n-1, n*limit/length), 1)
+		text = format(truncateText(value, n))
+	}
+	return slack.NewMrkdwn(label + text)
+}
+
+// inlineCode returns text escaped in inline code. A backtick would end the
+// inline code, so text with backticks is put in a code block instead.
+func inlineCode(text string) string {
+	if strings.Contains(text, "`") {
+		return slack.CodeBlock(text)
+	}
+	return "`" + slack.EscapeText(text) + "`"
+}
+
+// slackDate formats t so that Slack shows it in the reader's time zone.
+func slackDate(t time.Time) string {
+	return fmt.Sprintf("<!date^%d^{date_num} {time_secs}|%s>", t.Unix(), t.Format(time.RFC3339))
+}
+
+// quoteCommand joins args into a command line, quoting arguments that a
+// shell would split or expand.
+func quoteCommand(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "" || strings.ContainsFunc(arg, needsQuote) {
+			arg = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func needsQuote(r rune) bool {
+	switch {
+	case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9':
+		return false
+	case strings.ContainsRune("-_./=:,+@%", r):
+		return false
+	}
+	return true
+}
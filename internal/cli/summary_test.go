@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJobSummary_param(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := &jobSummary{
+		command:   "sh -c 'exit 3'",
+		host:      "web1",
+		dir:       "/srv/app",
+		start:     start,
+		end:       start.Add(90 * time.Second),
+		lines:     12,
+		bytes:     345,
+		exitCode:  3,
+		exitKnown: true,
+	}
+
+	param := s.param(slack.PostTextParam{Channel: "#test"})
+
+	if param.Channel != "#test" {
+		t.Errorf("expected %s; got %s", "#test", param.Channel)
+	}
+
+	expectedText := "Command finished (exit status 3) in 1m30s"
+	if param.Text != expectedText {
+		t.Errorf("expected %q; got %q", expectedText, param.Text)
+	}
+
+	if len(param.Attachments) != 1 {
+		t.Fatalf("expected 1 attachment; got %d", len(param.Attachments))
+	}
+	attachment := param.Attachments[0]
+	if attachment.Color != slack.ColorDanger {
+		t.Errorf("expected %s; got %s", slack.ColorDanger, attachment.Color)
+	}
+
+	var fields []string
+	for _, f := range attachment.Blocks[1].Fields {
+		fields = append(fields, f.Text)
+	}
+	expectedFields := []string{
+		"*Command*\n`sh -c 'exit 3'`",
+		"*Exit status*\n3",
+		"*Host*\nweb1",
+		"*Directory*\n/srv/app",
+		"*Started*\n<!date^1767323045^{date_num} {time_secs}|2026-01-02T03:04:05Z>",
+		"*Finished*\n<!date^1767323135^{date_num} {time_secs}|2026-01-02T03:05:35Z>",
+		"*Duration*\n1m30s",
+		"*Output*\n12 lines, 345 bytes",
+	}
+	if diff := cmp.Diff(expectedFields, fields); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+}
+
+func TestJobSummary_paramPiped(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := &jobSummary{
+		title: "nightly backup",
+		start: start,
+		end:   start.Add(time.Second),
+	}
+
+	param := s.param(slack.PostTextParam{})
+
+	expectedText := "nightly backup (done) in 1s"
+	if param.Text != expectedText {
+		t.Errorf("expected %q; got %q", expectedText, param.Text)
+	}
+	if color := param.Attachments[0].Color; color != slack.ColorGood {
+		t.Errorf("expected %s; got %s", slack.ColorGood, color)
+	}
+
+	// Slack rejects the whole message with a longer header
+	s.title = strings.Repeat("x", 200)
+	header := s.param(slack.PostTextParam{}).Attachments[0].Blocks[0].Text.Text
+	if n := len([]rune(header)); n != slack.MaxHeaderTextLength {
+		t.Errorf("expected %d; got %d", slack.MaxHeaderTextLength, n)
+	}
+	for _, f := range param.Attachments[0].Blocks[1].Fields {
+		if strings.HasPrefix(f.Text, "*Exit status*") || strings.HasPrefix(f.Text, "*Command*") {
+			t.Errorf("unexpected field %q", f.Text)
+		}
+	}
+}
+
+func TestJobSummary_paramLongCommand(t *testing.T) {
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := &jobSummary{
+		command: "sh -c '" + strings.Repeat("a < b && ", 500) + "'",
+		host:    "<web1>",
+		dir:     "/srv/`app`",
+		start:   start,
+		end:     start.Add(time.Second),
+	}
+
+	fields := s.param(slack.PostTextParam{}).Attachments[0].Blocks[1].Fields
+
+	// Slack rejects the whole message with a longer field
+	for _, f := range fields {
+		if n := len([]rune(f.Text)); n > slack.MaxFieldTextLength {
+			t.Errorf("got a field of %d characters, want at most %d", n, slack.MaxFieldTextLength)
+		}
+	}
+	if !strings.HasPrefix(fields[0].Text, "*Command*\n`sh -c 'a &lt; b &amp;&amp; ") || !strings.HasSuffix(fields[0].Text, "…`") {
+		t.Errorf("unexpected command field %q", fields[0].Text)
+	}
+
+	expected := []string{"*Host*\n&lt;web1&gt;", "*Directory*\n/srv/`app`"}
+	if diff := cmp.Diff(expected, []string{fields[1].Text, fields[2].Text}); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+
+	// Backticks would end the inline code
+	s.command = "echo `date`"
+	fields = s.param(slack.PostTextParam{}).Attachments[0].Blocks[1].Fields
+	if expected := "*Command*\n```\necho `date`\n```"; fields[0].Text != expected {
+		t.Errorf("expected %q; got %q", expected, fields[0].Text)
+	}
+}
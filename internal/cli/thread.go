@@ -0,0 +1,116 @@
+package cli
+
+import (
+	"context"
+
+	"github.com/catatsuy/notify_slack/internal/slack"
+)
+
+// threadPoster posts streamed output into a thread. The first message,
+// either the header or the first chunk of output, becomes the parent and
+// everything else is posted as replies to it.
+type threadPoster struct {
+	sClient   slack.Slack
+	param     slack.PostTextParam
+	header    string
+	limit     int
+	format    slack.TextFormat
+	broadcast bool
+
+	// channel ID and ts of the parent message
+	channel string
+	ts      string
+	// pending is the latest reply, held back when broadcast is enabled
+	// until it is known whether it is the last one
+	pending string
+}
+
+func newThreadPoster(sClient slack.Slack, param slack.PostTextParam, header string, limit int, format slack.TextFormat, broadcast bool) *threadPoster {
+	return &threadPoster{
+		sClient:   sClient,
+		param:     param,
+		header:    header,
+		limit:     limit,
+		format:    format,
+		broadcast: broadcast,
+	}
+}
+
+// flush posts output as replies, posting the parent first if needed. The
+// mention, if any, is put before the output.
+func (p *threadPoster) flush(ctx context.Context, output, mention string) error {
+	return p.post(ctx, output, mention)
+}
+
+// done posts the remaining output. The last reply is also sent to the
+// channel when broadcast is enabled, even if it came from an earlier flush.
+func (p *threadPoster) done(ctx context.Context, output, mention string) error {
+	if err := p.post(ctx, output, mention); err != nil {
+		return err
+	}
+	if p.pending == "" {
+		return nil
+	}
+	text := p.pending
+	p.pending = ""
+	return p.reply(ctx, []string{text}, true)
+}
+
+func (p *threadPoster) post(ctx context.Context, output, mention string) error {
+	chunks := p.format.Split(output, p.limit)
+	if len(chunks) == 0 {
+		return nil
+	}
+	chunks[0] = alert{mention: mention, text: chunks[0]}.message()
+	// mentioned tells whether chunks[0] is a reply with the mention
+	mentioned := mention != ""
+
+	if p.ts == "" {
+		parent := p.header
+		if parent == "" {
+			parent, chunks = chunks[0], chunks[1:]
+			mentioned = false
+		}
+
+		param := p.param
+		param.Text = parent
+		res, err := p.sClient.PostMessage(ctx, &param)
+		if err != nil {
+			return err
+		}
+		p.channel = res.Channel
+		p.ts = res.TS
+	}
+
+	// Hold back the last reply, which is broadcast if no more output comes.
+	// A reply with a mention is not held back so that nobody is notified
+	// late.
+	holdBack := p.broadcast && len(chunks) > 0 && !(mentioned && len(chunks) == 1)
+	if p.pending != "" {
+		chunks = append([]string{p.pending}, chunks...)
+		p.pending = ""
+	}
+	if holdBack {
+		chunks, p.pending = chunks[:len(chunks)-1], chunks[len(chunks)-1]
+	}
+
+	return p.reply(ctx, chunks, false)
+}
+
+// reply posts chunks as replies to the parent. The last one is also sent
+// to the channel if broadcast is set.
+func (p *threadPoster) reply(ctx context.Context, chunks []string, broadcast bool) error {
+	for i, text := range chunks {
+		param := p.param
+		param.Channel = p.channel
+		param.Text = text
+		param.ThreadTS = p.ts
+		param.ReplyBroadcast = broadcast && i == len(chunks)-1
+
+		if _, err := p.sClient.PostMessage(ctx, &param); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package cli
+
+import (
+	"context"
+	"testing"
+
+	"github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestThreadPoster(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []slack.PostTextParam
+	}{
+		{
+			name: "first output as parent",
+			want: []slack.PostTextParam{
+				{Channel: "#test", Text: "abc\n"},
+				{Channel: "C12345678", Text: "def\n", ThreadTS: "1.000000"},
+				{Channel: "C12345678", Text: "ghi\n", ThreadTS: "1.000000", ReplyBroadcast: true},
+			},
+		},
+		{
+			name:   "header as parent",
+			header: "deploy started",
+			want: []slack.PostTextParam{
+				{Channel: "#test", Text: "deploy started"},
+				{Channel: "C12345678", Text: "abc\n", ThreadTS: "1.000000"},
+				{Channel: "C12345678", Text: "def\n", ThreadTS: "1.000000"},
+				{Channel: "C12345678", Text: "ghi\n", ThreadTS: "1.000000", ReplyBroadcast: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []slack.PostTextParam
+			fake := &fakeSlackClient{
+				FakePostMessage: func(ctx context.Context, param *slack.PostTextParam) (*slack.ChatRes, error) {
+					got = append(got, *param)
+					return &slack.ChatRes{OK: true, Channel: "C12345678", TS: "1.000000"}, nil
+				},
+			}
+
+			p := newThreadPoster(fake, slack.PostTextParam{Channel: "#test"}, tt.header, slack.DefaultTextLimit, slack.FormatRaw, true)
+
+			if err := p.flush(t.Context(), "", ""); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.flush(t.Context(), "abc\n", ""); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.flush(t.Context(), "def\n", ""); err != nil {
+				t.Fatal(err)
+			}
+			if err := p.done(t.Context(), "ghi\n", ""); err != nil {
+				t.Fatal(err)
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestThreadPoster_broadcastWithoutFinalOutput(t *testing.T) {
+	var got []slack.PostTextParam
+	fake := &fakeSlackClient{
+		FakePostMessage: func(ctx context.Context, param *slack.PostTextParam) (*slack.ChatRes, error) {
+			got = append(got, *param)
+			return &slack.ChatRes{OK: true, Channel: "C12345678", TS: "1.000000"}, nil
+		},
+	}
+
+	p := newThreadPoster(fake, slack.PostTextParam{Channel: "#test"}, "", slack.DefaultTextLimit, slack.FormatRaw, true)
+
+	if err := p.flush(t.Context(), "abc\ndef\n", ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.flush(t.Context(), "ghi\n", ""); err != nil {
+		t.Fatal(err)
+	}
+	// All the output was flushed before the input ended
+	if err := p.done(t.Context(), "", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The last reply is posted once, and sent to the channel
+	want := []slack.PostTextParam{
+		{Channel: "#test", Text: "abc\ndef\n"},
+		{Channel: "C12345678", Text: "ghi\n", ThreadTS: "1.000000", ReplyBroadcast: true},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+}
+
+func TestThreadPoster_broadcastMention(t *testing.T) {
+	var got []slack.PostTextParam
+	fake := &fakeSlackClient{
+		FakePostMessage: func(ctx context.Context, param *slack.PostTextParam) (*slack.ChatRes, error) {
+			got = append(got, *param)
+			return &slack.ChatRes{OK: true, Channel: "C12345678", TS: "1.000000"}, nil
+		},
+	}
+
+	p := newThreadPoster(fake, slack.PostTextParam{Channel: "#test"}, "deploy started", slack.DefaultTextLimit, slack.FormatRaw, true)
+
+	if err := p.flush(t.Context(), "abc\n", ""); err != nil {
+		t.Fatal(err)
+	}
+	// A reply with a mention is posted right away
+	if err := p.flush(t.Context(), "error\n", "<!here>"); err != nil {
+		t.Fatal(err)
+	}
+	want := []slack.PostTextParam{
+		{Channel: "#test", Text: "deploy started"},
+		{Channel: "C12345678", Text: "abc\n", ThreadTS: "1.000000"},
+		{Channel: "C12345678", Text: "<!here>\nerror\n", ThreadTS: "1.000000"},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+
+	if err := p.done(t.Context(), "", ""); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("expected no more messages; got %+v", got[len(want):])
+	}
+}
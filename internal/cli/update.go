@@ -0,0 +1,108 @@
+package cli
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/catatsuy/notify_slack/internal/slack"
+)
+
+// defaultUpdateLines is the number of lines shown in the message in update
+// mode unless configured otherwise.
+const defaultUpdateLines = 20
+
+// messageUpdater keeps streamed output in a single message. The message is
+// posted on the first flush and rewritten with chat.update afterwards,
+// showing only the latest lines. A new message is started when the window
+// no longer fits in one message.
+type messageUpdater struct {
+	sClient  slack.Slack
+	param    slack.PostTextParam
+	maxLines int
+	limit    int
+	format   slack.TextFormat
+
+	// lines shown in the current message
+	lines   []string
+	channel string
+	ts      string
+}
+
+func newMessageUpdater(sClient slack.Slack, param slack.PostTextParam, maxLines, limit int, format slack.TextFormat) *messageUpdater {
+	if maxLines <= 0 {
+		maxLines = defaultUpdateLines
+	}
+
+	return &messageUpdater{
+		sClient:  sClient,
+		param:    param,
+		maxLines: maxLines,
+		limit:    limit,
+		format:   format,
+	}
+}
+
+// flush shows output in the message. Output with a mention starts a new
+// message with the mention before the lines, since Slack doesn't notify
+// for mentions added by an update.
+func (u *messageUpdater) flush(ctx context.Context, output, mention string) error {
+	if output == "" {
+		return nil
+	}
+
+	newLines := strings.Split(strings.TrimSuffix(output, "\n"), "\n")
+
+	if u.ts != "" && mention == "" {
+		lines := u.window(append(u.lines[:len(u.lines):len(u.lines)], newLines...))
+		text := u.format.Apply(strings.Join(lines, "\n"))
+		if utf8.RuneCountInString(text) <= u.limit {
+			err := u.sClient.UpdateMessage(ctx, &slack.UpdateMessageParam{
+				Channel: u.channel,
+				TS:      u.ts,
+				Text:    text,
+			})
+			if err != nil {
+				return err
+			}
+			u.lines = lines
+			return nil
+		}
+	}
+
+	return u.start(ctx, u.window(newLines), mention)
+}
+
+// start posts lines as a new message which following flushes rewrite.
+// The mention is put before the first of them.
+func (u *messageUpdater) start(ctx context.Context, lines []string, mention string) error {
+	// Split before formatting to keep the lines shown in each message
+	chunks := u.format.Chunks(strings.Join(lines, "\n"), u.limit)
+
+	for i, text := range chunks {
+		param := u.param
+		param.Text = u.format.Apply(text)
+		if i == 0 {
+			param.Text = alert{mention: mention, text: param.Text}.message()
+		}
+
+		res, err := u.sClient.PostMessage(ctx, &param)
+		if err != nil {
+			return err
+		}
+
+		u.channel = res.Channel
+		u.ts = res.TS
+		u.lines = strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+	}
+
+	return nil
+}
+
+// window returns the latest maxLines lines.
+func (u *messageUpdater) window(lines []string) []string {
+	if len(lines) > u.maxLines {
+		return lines[len(lines)-u.maxLines:]
+	}
+	return lines
+}
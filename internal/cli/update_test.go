@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMessageUpdater(t *testing.T) {
+	var posted []string
+	var updated []string
+
+	fake := &fakeSlackClient{
+		FakePostMessage: func(ctx context.Context, param *slack.PostTextParam) (*slack.ChatRes, error) {
+			if param.Channel != "#test" {
+				t.Errorf("expected %s; got %s", "#test", param.Channel)
+			}
+			posted = append(posted, param.Text)
+			return &slack.ChatRes{OK: true, Channel: "C12345678", TS: fmt.Sprintf("%d.000000", len(posted))}, nil
+		},
+		FakeUpdateMessage: func(ctx context.Context, param *slack.UpdateMessageParam) error {
+			if param.Channel != "C12345678" {
+				t.Errorf("expected %s; got %s", "C12345678", param.Channel)
+			}
+			if want := fmt.Sprintf("%d.000000", len(posted)); param.TS != want {
+				t.Errorf("expected %s; got %s", want, param.TS)
+			}
+			updated = append(updated, param.Text)
+			return nil
+		},
+	}
+
+	u := newMessageUpdater(fake, slack.PostTextParam{Channel: "#test"}, 3, 12, slack.FormatRaw)
+
+	for _, output := range []string{"a\nb\n", "", "c\nd\n", "eeeeeeeeee\n"} {
+		if err := u.flush(t.Context(), output, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The window keeps the latest 3 lines. "d\neeeeeeeeee" does not fit in
+	// 12 characters, so a new message is started.
+	if diff := cmp.Diff([]string{"a\nb", "eeeeeeeeee"}, posted); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+	if diff := cmp.Diff([]string{"b\nc\nd"}, updated); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+}
+
+func TestMessageUpdater_format(t *testing.T) {
+	var posted []string
+	var updated []string
+
+	fake := &fakeSlackClient{
+		FakePostMessage: func(ctx context.Context, param *slack.PostTextParam) (*slack.ChatRes, error) {
+			posted = append(posted, param.Text)
+			return &slack.ChatRes{OK: true, Channel: "C12345678", TS: "1.000000"}, nil
+		},
+		FakeUpdateMessage: func(ctx context.Context, param *slack.UpdateMessageParam) error {
+			updated = append(updated, param.Text)
+			return nil
+		},
+	}
+
+	u := newMessageUpdater(fake, slack.PostTextParam{Channel: "#test"}, 3, slack.DefaultTextLimit, slack.FormatCode)
+
+	if err := u.flush(t.Context(), "a<b\n", "<!here>"); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.flush(t.Context(), "c\n", ""); err != nil {
+		t.Fatal(err)
+	}
+	// Slack doesn't notify for mentions added by an update, so a later
+	// mention starts a new message
+	if err := u.flush(t.Context(), "d\n", "<!channel>"); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.flush(t.Context(), "e\n", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The mention is left out of the code block and shown until the next flush
+	expectedPosted := []string{"<!here>\n```\na&lt;b\n```", "<!channel>\n```\nd\n```"}
+	if diff := cmp.Diff(expectedPosted, posted); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+	expectedUpdated := []string{"```\na&lt;b\nc\n```", "```\nd\ne\n```"}
+	if diff := cmp.Diff(expectedUpdated, updated); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	toml "github.com/pelletier/go-toml/v2"
@@ -15,13 +16,72 @@ var (
 
 type Config struct {
 	SlackURL       string
+	SlackURLs      []string
 	Token          string
+	Via            string
 	Channel        string
+	Channels       []string
 	SnippetChannel string
 	ChannelID      string
 	Username       string
 	IconEmoji      string
 	Duration       time.Duration
+	MessageLimit   int
+	MaxLineBytes   int
+	Format         string
+
+	RetryMaxAttempts int
+	RetryMaxElapsed  time.Duration
+
+	Summary      bool
+	SummaryTitle string
+
+	FlushBytes     int
+	FlushLines     int
+	FlushQuiet     time.Duration
+	FlushMaxDelay  time.Duration
+	FlushFirstLine bool
+	OnError        string
+	Dedupe         string
+
+	SnippetLines        int
+	SnippetPreviewLines int
+
+	RedactPatterns []string
+
+	FilterInclude []string
+	FilterExclude []string
+	FilterContext int
+
+	AlertRules    []AlertRule
+	AlertCooldown time.Duration
+	AlertIfSilent time.Duration
+	AlertRecovery bool
+
+	Follow []FollowSource
+
+	Archive     bool
+	ArchiveGzip bool
+
+	SyslogListen   string
+	SyslogSeverity string
+	SyslogChannel  string
+}
+
+// FollowSource is a file to follow, or a glob matching files to follow. The
+// lines are posted to Channel if set.
+type FollowSource struct {
+	Path    string
+	Channel string
+}
+
+// AlertRule highlights the lines matching Pattern with Emoji and Color, and
+// mentions Mention, e.g. <!here>, <!subteam^ID> or <@U123>.
+type AlertRule struct {
+	Pattern string
+	Mention string
+	Emoji   string
+	Color   string
 }
 
 func NewConfig() *Config {
@@ -29,7 +89,7 @@ func NewConfig() *Config {
 }
 
 func (c *Config) LoadEnv() error {
-	if c.SlackURL == "" {
+	if c.SlackURL == "" && len(c.SlackURLs) == 0 {
 		c.SlackURL = os.Getenv("NOTIFY_SLACK_WEBHOOK_URL")
 	}
 
@@ -37,7 +97,11 @@ func (c *Config) LoadEnv() error {
 		c.Token = os.Getenv("NOTIFY_SLACK_TOKEN")
 	}
 
-	if c.Channel == "" {
+	if c.Via == "" {
+		c.Via = os.Getenv("NOTIFY_SLACK_VIA")
+	}
+
+	if c.Channel == "" && len(c.Channels) == 0 {
 		c.Channel = os.Getenv("NOTIFY_SLACK_CHANNEL")
 	}
 
@@ -68,22 +132,129 @@ func (c *Config) LoadEnv() error {
 		c.Duration = duration
 	}
 
+	if c.MessageLimit == 0 {
+		limitStr := os.Getenv("NOTIFY_SLACK_MESSAGE_LIMIT")
+		if limitStr != "" {
+			limit, err := strconv.Atoi(limitStr)
+			if err != nil {
+				return fmt.Errorf("incorrect value to message_limit option from NOTIFY_SLACK_MESSAGE_LIMIT: %s: %w", limitStr, err)
+			}
+			c.MessageLimit = limit
+		}
+	}
+
+	if c.MaxLineBytes == 0 {
+		maxLineBytesStr := os.Getenv("NOTIFY_SLACK_MAX_LINE_BYTES")
+		if maxLineBytesStr != "" {
+			maxLineBytes, err := strconv.Atoi(maxLineBytesStr)
+			if err != nil {
+				return fmt.Errorf("incorrect value to max_line_bytes option from NOTIFY_SLACK_MAX_LINE_BYTES: %s: %w", maxLineBytesStr, err)
+			}
+			c.MaxLineBytes = maxLineBytes
+		}
+	}
+
+	if c.RetryMaxAttempts == 0 {
+		attemptsStr := os.Getenv("NOTIFY_SLACK_RETRY_MAX_ATTEMPTS")
+		if attemptsStr != "" {
+			attempts, err := strconv.Atoi(attemptsStr)
+			if err != nil {
+				return fmt.Errorf("incorrect value to retry_max_attempts option from NOTIFY_SLACK_RETRY_MAX_ATTEMPTS: %s: %w", attemptsStr, err)
+			}
+			c.RetryMaxAttempts = attempts
+		}
+	}
+
+	if c.RetryMaxElapsed == 0 {
+		elapsedStr := os.Getenv("NOTIFY_SLACK_RETRY_MAX_ELAPSED")
+		if elapsedStr != "" {
+			elapsed, err := time.ParseDuration(elapsedStr)
+			if err != nil {
+				return fmt.Errorf("incorrect value to retry_max_elapsed option from NOTIFY_SLACK_RETRY_MAX_ELAPSED: %s: %w", elapsedStr, err)
+			}
+			c.RetryMaxElapsed = elapsed
+		}
+	}
+
 	return nil
 }
 
 type slackConfig struct {
 	URL            string
+	URLs           []string
 	Token          string
+	Via            string
 	Channel        string
+	Channels       []string
 	SnippetChannel string `toml:"snippet_channel"`
 	ChannelID      string `toml:"channel_id"`
 	Username       string
 	IconEmoji      string `toml:"icon_emoji"`
 	Interval       string
+	MessageLimit   int `toml:"message_limit"`
+	MaxLineBytes   int `toml:"max_line_bytes"`
+	Format         string
+
+	RetryMaxAttempts int    `toml:"retry_max_attempts"`
+	RetryMaxElapsed  string `toml:"retry_max_elapsed"`
+}
+
+type summaryConfig struct {
+	Enabled bool
+	Title   string
+}
+
+type flushConfig struct {
+	Bytes     int
+	Lines     int
+	Quiet     string
+	MaxDelay  string `toml:"max_delay"`
+	FirstLine bool   `toml:"first_line"`
+	OnError   string `toml:"on_error"`
+	Dedupe    string
+
+	SnippetLines        int `toml:"snippet_lines"`
+	SnippetPreviewLines int `toml:"snippet_preview_lines"`
+}
+
+type redactConfig struct {
+	Patterns []string
+}
+
+type filterConfig struct {
+	Include []string
+	Exclude []string
+	Context int
+}
+
+type alertConfig struct {
+	Cooldown string
+	IfSilent string `toml:"if_silent"`
+	Recovery bool
+	Rules    []AlertRule
+}
+
+type archiveConfig struct {
+	Enabled bool
+	Gzip    bool
+}
+
+type syslogConfig struct {
+	Listen   string
+	Severity string
+	Channel  string
 }
 
 type rootConfig struct {
-	Slack slackConfig
+	Slack   slackConfig
+	Summary summaryConfig
+	Flush   flushConfig
+	Redact  redactConfig
+	Filter  filterConfig
+	Alert   alertConfig
+	Follow  []FollowSource
+	Archive archiveConfig
+	Syslog  syslogConfig
 }
 
 func (c *Config) LoadTOML(filename string) error {
@@ -101,21 +272,26 @@ func (c *Config) LoadTOML(filename string) error {
 
 	slackConfig := cfg.Slack
 
-	if c.SlackURL == "" {
-		if slackConfig.URL != "" {
-			c.SlackURL = slackConfig.URL
-		}
+	// The URLs given with options replace all the URLs in the file
+	if c.SlackURL == "" && len(c.SlackURLs) == 0 {
+		c.SlackURL = slackConfig.URL
+		c.SlackURLs = slackConfig.URLs
 	}
 	if c.Token == "" {
 		if slackConfig.Token != "" {
 			c.Token = slackConfig.Token
 		}
 	}
-	if c.Channel == "" {
-		if slackConfig.Channel != "" {
-			c.Channel = slackConfig.Channel
+	if c.Via == "" {
+		if slackConfig.Via != "" {
+			c.Via = slackConfig.Via
 		}
 	}
+	// The channels given with options replace all the channels in the file
+	if c.Channel == "" && len(c.Channels) == 0 {
+		c.Channel = slackConfig.Channel
+		c.Channels = slackConfig.Channels
+	}
 	if c.Username == "" {
 		if slackConfig.Username != "" {
 			c.Username = slackConfig.Username
@@ -143,6 +319,143 @@ func (c *Config) LoadTOML(filename string) error {
 		c.Duration = duration
 	}
 
+	if c.MessageLimit == 0 {
+		c.MessageLimit = slackConfig.MessageLimit
+	}
+
+	if c.MaxLineBytes == 0 {
+		c.MaxLineBytes = slackConfig.MaxLineBytes
+	}
+
+	if c.Format == "" {
+		c.Format = slackConfig.Format
+	}
+
+	if c.RetryMaxAttempts == 0 {
+		c.RetryMaxAttempts = slackConfig.RetryMaxAttempts
+	}
+
+	if c.RetryMaxElapsed == 0 && slackConfig.RetryMaxElapsed != "" {
+		elapsed, err := time.ParseDuration(slackConfig.RetryMaxElapsed)
+		if err != nil {
+			return fmt.Errorf("incorrect value to retry_max_elapsed option: %s: %w", slackConfig.RetryMaxElapsed, err)
+		}
+		c.RetryMaxElapsed = elapsed
+	}
+
+	summaryConfig := cfg.Summary
+
+	if !c.Summary {
+		c.Summary = summaryConfig.Enabled
+	}
+	if c.SummaryTitle == "" {
+		c.SummaryTitle = summaryConfig.Title
+	}
+
+	flushConfig := cfg.Flush
+
+	if c.FlushBytes == 0 {
+		c.FlushBytes = flushConfig.Bytes
+	}
+	if c.FlushLines == 0 {
+		c.FlushLines = flushConfig.Lines
+	}
+	if c.FlushQuiet == 0 && flushConfig.Quiet != "" {
+		quiet, err := time.ParseDuration(flushConfig.Quiet)
+		if err != nil {
+			return fmt.Errorf("incorrect value to quiet option: %s: %w", flushConfig.Quiet, err)
+		}
+		c.FlushQuiet = quiet
+	}
+	if c.FlushMaxDelay == 0 && flushConfig.MaxDelay != "" {
+		maxDelay, err := time.ParseDuration(flushConfig.MaxDelay)
+		if err != nil {
+			return fmt.Errorf("incorrect value to max_delay option: %s: %w", flushConfig.MaxDelay, err)
+		}
+		c.FlushMaxDelay = maxDelay
+	}
+	if !c.FlushFirstLine {
+		c.FlushFirstLine = flushConfig.FirstLine
+	}
+	if c.OnError == "" {
+		c.OnError = flushConfig.OnError
+	}
+	if c.Dedupe == "" {
+		c.Dedupe = flushConfig.Dedupe
+	}
+	if c.SnippetLines == 0 {
+		c.SnippetLines = flushConfig.SnippetLines
+	}
+	if c.SnippetPreviewLines == 0 {
+		c.SnippetPreviewLines = flushConfig.SnippetPreviewLines
+	}
+
+	redactConfig := cfg.Redact
+
+	if len(c.RedactPatterns) == 0 {
+		c.RedactPatterns = redactConfig.Patterns
+	}
+
+	filterConfig := cfg.Filter
+
+	if len(c.FilterInclude) == 0 {
+		c.FilterInclude = filterConfig.Include
+	}
+	if len(c.FilterExclude) == 0 {
+		c.FilterExclude = filterConfig.Exclude
+	}
+	if c.FilterContext == 0 {
+		c.FilterContext = filterConfig.Context
+	}
+
+	if len(c.Follow) == 0 {
+		c.Follow = cfg.Follow
+	}
+
+	alertConfig := cfg.Alert
+
+	if len(c.AlertRules) == 0 {
+		c.AlertRules = alertConfig.Rules
+	}
+	if c.AlertCooldown == 0 && alertConfig.Cooldown != "" {
+		cooldown, err := time.ParseDuration(alertConfig.Cooldown)
+		if err != nil {
+			return fmt.Errorf("incorrect value to cooldown option: %s: %w", alertConfig.Cooldown, err)
+		}
+		c.AlertCooldown = cooldown
+	}
+	if c.AlertIfSilent == 0 && alertConfig.IfSilent != "" {
+		ifSilent, err := time.ParseDuration(alertConfig.IfSilent)
+		if err != nil {
+			return fmt.Errorf("incorrect value to if_silent option: %s: %w", alertConfig.IfSilent, err)
+		}
+		c.AlertIfSilent = ifSilent
+	}
+	if !c.AlertRecovery {
+		c.AlertRecovery = alertConfig.Recovery
+	}
+
+	archiveConfig := cfg.Archive
+
+	if !c.Archive {
+		c.Archive = archiveConfig.Enabled
+	}
+	if !c.ArchiveGzip {
+		c.ArchiveGzip = archiveConfig.Gzip
+	}
+
+	syslogConfig := cfg.Syslog
+
+	if c.SyslogListen == "" {
+		c.SyslogListen = syslogConfig.Listen
+	}
+	if c.SyslogSeverity == "" {
+		c.SyslogSeverity = syslogConfig.Severity
+	}
+	if c.SyslogChannel == "" {
+		c.SyslogChannel = syslogConfig.Channel
+	}
+
 	return nil
 }
 
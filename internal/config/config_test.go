@@ -8,6 +8,7 @@ import (
 	"time"
 
 	. "github.com/catatsuy/notify_slack/internal/config"
+	"github.com/google/go-cmp/cmp"
 )
 
 func TestLoadTOML(t *testing.T) {
@@ -20,14 +21,26 @@ func TestLoadTOML(t *testing.T) {
 	if c.SlackURL != expectedSlackURL {
 		t.Errorf("got %s, want %s", c.SlackURL, expectedSlackURL)
 	}
+	expectedSlackURLs := []string{"https://hooks.slack.com/bbbbb", "https://hooks.slack.com/ccccc"}
+	if diff := cmp.Diff(expectedSlackURLs, c.SlackURLs); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
 	expectedToken := "xoxp-token"
 	if c.Token != expectedToken {
 		t.Errorf("got %s, want %s", c.Token, expectedToken)
 	}
+	expectedVia := "unix:///run/notify_slack.sock"
+	if c.Via != expectedVia {
+		t.Errorf("got %s, want %s", c.Via, expectedVia)
+	}
 	expectedChannel := "#test"
 	if c.Channel != expectedChannel {
 		t.Errorf("got %s, want %s", c.Channel, expectedChannel)
 	}
+	expectedChannels := []string{"#ops", "#audit"}
+	if diff := cmp.Diff(expectedChannels, c.Channels); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
 	expectedChannelID := "C12345678"
 	if c.ChannelID != expectedChannelID {
 		t.Errorf("got %s, want %s", c.ChannelID, expectedChannelID)
@@ -44,6 +57,127 @@ func TestLoadTOML(t *testing.T) {
 	if c.Duration != expectedInterval {
 		t.Errorf("got %+v, want %+v", c.Duration, expectedInterval)
 	}
+	expectedMessageLimit := 3000
+	if c.MessageLimit != expectedMessageLimit {
+		t.Errorf("got %d, want %d", c.MessageLimit, expectedMessageLimit)
+	}
+	expectedMaxLineBytes := 8192
+	if c.MaxLineBytes != expectedMaxLineBytes {
+		t.Errorf("got %d, want %d", c.MaxLineBytes, expectedMaxLineBytes)
+	}
+	expectedFormat := "code"
+	if c.Format != expectedFormat {
+		t.Errorf("got %s, want %s", c.Format, expectedFormat)
+	}
+	expectedRetryMaxAttempts := 3
+	if c.RetryMaxAttempts != expectedRetryMaxAttempts {
+		t.Errorf("got %d, want %d", c.RetryMaxAttempts, expectedRetryMaxAttempts)
+	}
+	expectedRetryMaxElapsed := 30 * time.Second
+	if c.RetryMaxElapsed != expectedRetryMaxElapsed {
+		t.Errorf("got %+v, want %+v", c.RetryMaxElapsed, expectedRetryMaxElapsed)
+	}
+	if !c.Summary {
+		t.Errorf("got %t, want %t", c.Summary, true)
+	}
+	expectedSummaryTitle := "nightly backup"
+	if c.SummaryTitle != expectedSummaryTitle {
+		t.Errorf("got %s, want %s", c.SummaryTitle, expectedSummaryTitle)
+	}
+	expectedFlushBytes := 2048
+	if c.FlushBytes != expectedFlushBytes {
+		t.Errorf("got %d, want %d", c.FlushBytes, expectedFlushBytes)
+	}
+	expectedFlushLines := 50
+	if c.FlushLines != expectedFlushLines {
+		t.Errorf("got %d, want %d", c.FlushLines, expectedFlushLines)
+	}
+	expectedFlushQuiet := 200 * time.Millisecond
+	if c.FlushQuiet != expectedFlushQuiet {
+		t.Errorf("got %+v, want %+v", c.FlushQuiet, expectedFlushQuiet)
+	}
+	expectedFlushMaxDelay := 5 * time.Second
+	if c.FlushMaxDelay != expectedFlushMaxDelay {
+		t.Errorf("got %+v, want %+v", c.FlushMaxDelay, expectedFlushMaxDelay)
+	}
+	if !c.FlushFirstLine {
+		t.Errorf("got %t, want %t", c.FlushFirstLine, true)
+	}
+	expectedOnError := "abort"
+	if c.OnError != expectedOnError {
+		t.Errorf("got %s, want %s", c.OnError, expectedOnError)
+	}
+	expectedDedupe := "normalize"
+	if c.Dedupe != expectedDedupe {
+		t.Errorf("got %s, want %s", c.Dedupe, expectedDedupe)
+	}
+	expectedSnippetLines := 500
+	if c.SnippetLines != expectedSnippetLines {
+		t.Errorf("got %d, want %d", c.SnippetLines, expectedSnippetLines)
+	}
+	expectedSnippetPreviewLines := 3
+	if c.SnippetPreviewLines != expectedSnippetPreviewLines {
+		t.Errorf("got %d, want %d", c.SnippetPreviewLines, expectedSnippetPreviewLines)
+	}
+	expectedRedactPatterns := []string{`password=(\S+)`, "internal-[0-9]+"}
+	if diff := cmp.Diff(expectedRedactPatterns, c.RedactPatterns); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+	expectedFilterInclude := []string{"ERROR", "WARN"}
+	if diff := cmp.Diff(expectedFilterInclude, c.FilterInclude); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+	expectedFilterExclude := []string{"healthcheck"}
+	if diff := cmp.Diff(expectedFilterExclude, c.FilterExclude); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+	expectedFilterContext := 2
+	if c.FilterContext != expectedFilterContext {
+		t.Errorf("got %d, want %d", c.FilterContext, expectedFilterContext)
+	}
+	expectedAlertRules := []AlertRule{
+		{Pattern: "panic|FAILED", Mention: "<!here>", Emoji: ":fire:", Color: "danger"},
+		{Pattern: "WARN", Mention: "<@U123>"},
+	}
+	if diff := cmp.Diff(expectedAlertRules, c.AlertRules); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+	expectedAlertCooldown := 10 * time.Minute
+	if c.AlertCooldown != expectedAlertCooldown {
+		t.Errorf("got %+v, want %+v", c.AlertCooldown, expectedAlertCooldown)
+	}
+	expectedAlertIfSilent := 15 * time.Minute
+	if c.AlertIfSilent != expectedAlertIfSilent {
+		t.Errorf("got %+v, want %+v", c.AlertIfSilent, expectedAlertIfSilent)
+	}
+	if !c.AlertRecovery {
+		t.Errorf("got %v, want %v", c.AlertRecovery, true)
+	}
+	expectedFollow := []FollowSource{
+		{Path: "/var/log/app.log"},
+		{Path: "/var/log/db/*.log", Channel: "#db"},
+	}
+	if diff := cmp.Diff(expectedFollow, c.Follow); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+	if !c.Archive {
+		t.Errorf("got %t, want %t", c.Archive, true)
+	}
+	if !c.ArchiveGzip {
+		t.Errorf("got %t, want %t", c.ArchiveGzip, true)
+	}
+	expectedSyslogListen := "udp://127.0.0.1:5514"
+	if c.SyslogListen != expectedSyslogListen {
+		t.Errorf("got %s, want %s", c.SyslogListen, expectedSyslogListen)
+	}
+	expectedSyslogSeverity := "warning"
+	if c.SyslogSeverity != expectedSyslogSeverity {
+		t.Errorf("got %s, want %s", c.SyslogSeverity, expectedSyslogSeverity)
+	}
+	expectedSyslogChannel := "#syslog"
+	if c.SyslogChannel != expectedSyslogChannel {
+		t.Errorf("got %s, want %s", c.SyslogChannel, expectedSyslogChannel)
+	}
 }
 
 func TestLoadTOML_Deprecated(t *testing.T) {
@@ -62,20 +196,34 @@ func TestLoadTOML_Deprecated(t *testing.T) {
 func TestLoadEnv(t *testing.T) {
 	expectedSlackURL := "https://hooks.slack.com/aaaaa"
 	expectedToken := "xoxp-token"
+	expectedVia := "unix:///run/notify_slack.sock"
 	expectedChannel := "#test"
 	expectedChannelID := "C12345678"
 	expectedUsername := "deploy!"
 	expectedIconEmoji := ":rocket:"
 	expectedIntervalStr := "2s"
 	expectedInterval := time.Duration(2 * time.Second)
+	expectedMessageLimitStr := "3000"
+	expectedMessageLimit := 3000
+	expectedMaxLineBytesStr := "8192"
+	expectedMaxLineBytes := 8192
+	expectedRetryMaxAttemptsStr := "3"
+	expectedRetryMaxAttempts := 3
+	expectedRetryMaxElapsedStr := "30s"
+	expectedRetryMaxElapsed := 30 * time.Second
 
 	t.Setenv("NOTIFY_SLACK_WEBHOOK_URL", expectedSlackURL)
 	t.Setenv("NOTIFY_SLACK_TOKEN", expectedToken)
+	t.Setenv("NOTIFY_SLACK_VIA", expectedVia)
 	t.Setenv("NOTIFY_SLACK_CHANNEL", expectedChannel)
 	t.Setenv("NOTIFY_SLACK_CHANNEL_ID", expectedChannelID)
 	t.Setenv("NOTIFY_SLACK_USERNAME", expectedUsername)
 	t.Setenv("NOTIFY_SLACK_ICON_EMOJI", expectedIconEmoji)
 	t.Setenv("NOTIFY_SLACK_INTERVAL", expectedIntervalStr)
+	t.Setenv("NOTIFY_SLACK_MESSAGE_LIMIT", expectedMessageLimitStr)
+	t.Setenv("NOTIFY_SLACK_MAX_LINE_BYTES", expectedMaxLineBytesStr)
+	t.Setenv("NOTIFY_SLACK_RETRY_MAX_ATTEMPTS", expectedRetryMaxAttemptsStr)
+	t.Setenv("NOTIFY_SLACK_RETRY_MAX_ELAPSED", expectedRetryMaxElapsedStr)
 
 	c := NewConfig()
 	err := c.LoadEnv()
@@ -91,6 +239,10 @@ func TestLoadEnv(t *testing.T) {
 		t.Errorf("got %s, want %s", c.Token, expectedToken)
 	}
 
+	if c.Via != expectedVia {
+		t.Errorf("got %s, want %s", c.Via, expectedVia)
+	}
+
 	if c.Channel != expectedChannel {
 		t.Errorf("got %s, want %s", c.Channel, expectedChannel)
 	}
@@ -110,6 +262,56 @@ func TestLoadEnv(t *testing.T) {
 	if c.Duration != expectedInterval {
 		t.Errorf("got %+v, want %+v", c.Duration, expectedInterval)
 	}
+
+	if c.MessageLimit != expectedMessageLimit {
+		t.Errorf("got %d, want %d", c.MessageLimit, expectedMessageLimit)
+	}
+
+	if c.MaxLineBytes != expectedMaxLineBytes {
+		t.Errorf("got %d, want %d", c.MaxLineBytes, expectedMaxLineBytes)
+	}
+
+	if c.RetryMaxAttempts != expectedRetryMaxAttempts {
+		t.Errorf("got %d, want %d", c.RetryMaxAttempts, expectedRetryMaxAttempts)
+	}
+
+	if c.RetryMaxElapsed != expectedRetryMaxElapsed {
+		t.Errorf("got %+v, want %+v", c.RetryMaxElapsed, expectedRetryMaxElapsed)
+	}
+}
+
+func TestLoadTOML_slackURLs(t *testing.T) {
+	c := NewConfig()
+	c.SlackURL = "https://hooks.slack.com/zzzzz"
+	err := c.LoadTOML("./testdata/config.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The URLs in the file are not added to the given one
+	if c.SlackURL != "https://hooks.slack.com/zzzzz" {
+		t.Errorf("got %s, want %s", c.SlackURL, "https://hooks.slack.com/zzzzz")
+	}
+	if len(c.SlackURLs) != 0 {
+		t.Errorf("expected no more URLs, got %q", c.SlackURLs)
+	}
+}
+
+func TestLoadTOML_channels(t *testing.T) {
+	c := NewConfig()
+	c.Channel = "#zzz"
+	err := c.LoadTOML("./testdata/config.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The channels in the file are not added to the given one
+	if c.Channel != "#zzz" {
+		t.Errorf("got %s, want %s", c.Channel, "#zzz")
+	}
+	if len(c.Channels) != 0 {
+		t.Errorf("expected no more channels, got %q", c.Channels)
+	}
 }
 
 func TestLoadEnv_Deprecated(t *testing.T) {
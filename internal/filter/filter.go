@@ -0,0 +1,119 @@
+// Package filter selects the lines to post with regular expressions.
+package filter
+
+import (
+	"fmt"
+	"regexp"
+)
+
+// Separator is emitted between groups of lines that are not adjacent in
+// the input when context lines are shown, like grep does.
+const Separator = "--"
+
+// Filter passes the lines that match any of the include patterns, or all
+// lines if there are none, except the lines that match any of the exclude
+// patterns. Excluded lines are never passed, not even as context.
+type Filter struct {
+	include []*regexp.Regexp
+	exclude []*regexp.Regexp
+	context int
+
+	// before holds up to context lines preceding the next match
+	before [][]byte
+	// after is the number of lines still to pass after the last match
+	after int
+	// skipped tells whether lines were dropped since a line was passed
+	skipped bool
+	passed  bool
+}
+
+// New returns a Filter that also passes context lines before and after
+// each matching line.
+func New(include, exclude []string, context int) (*Filter, error) {
+	f := &Filter{context: max(context, 0)}
+
+	var err error
+	f.include, err = compile("include", include)
+	if err != nil {
+		return nil, err
+	}
+	f.exclude, err = compile("exclude", exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	return f, nil
+}
+
+func compile(option string, patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(patterns))
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect value to %s option: %s: %w", option, p, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// Line passes line to emit if it is selected, preceded by the context lines
+// kept for it. Lines are passed in the input order.
+func (f *Filter) Line(line []byte, emit func([]byte)) {
+	if matchAny(f.exclude, line) {
+		f.skip()
+		return
+	}
+
+	if len(f.include) == 0 || matchAny(f.include, line) {
+		if f.skipped && f.passed && f.context > 0 && len(f.include) > 0 {
+			emit([]byte(Separator))
+		}
+		for _, b := range f.before {
+			emit(b)
+		}
+		f.before = f.before[:0]
+		f.pass(line, emit)
+		f.after = f.context
+		return
+	}
+
+	if f.after > 0 {
+		f.after--
+		f.pass(line, emit)
+		return
+	}
+
+	if f.context == 0 {
+		f.skip()
+		return
+	}
+
+	// Keep the line in case a match follows
+	if len(f.before) == f.context {
+		f.before = append(f.before[:0], f.before[1:]...)
+		f.skipped = true
+	}
+	f.before = append(f.before, append([]byte(nil), line...))
+}
+
+func (f *Filter) pass(line []byte, emit func([]byte)) {
+	emit(line)
+	f.passed = true
+	f.skipped = false
+}
+
+func (f *Filter) skip() {
+	// Kept lines are no longer adjacent to the next match
+	f.before = f.before[:0]
+	f.skipped = true
+}
+
+func matchAny(res []*regexp.Regexp, line []byte) bool {
+	for _, re := range res {
+		if re.Match(line) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package filter_test
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/catatsuy/notify_slack/internal/filter"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestFilter(t *testing.T) {
+	input := []string{"a", "b", "ERROR 1", "c", "d", "e", "f", "ERROR 2", "g", "ERROR 3 ignored", "h"}
+
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		context int
+		want    []string
+	}{
+		{
+			name: "no patterns",
+			want: input,
+		},
+		{
+			name:    "include",
+			include: []string{"^ERROR"},
+			want:    []string{"ERROR 1", "ERROR 2", "ERROR 3 ignored"},
+		},
+		{
+			name:    "include and exclude",
+			include: []string{"^ERROR"},
+			exclude: []string{"ignored"},
+			want:    []string{"ERROR 1", "ERROR 2"},
+		},
+		{
+			name:    "exclude only",
+			exclude: []string{"^[a-e]$"},
+			context: 1,
+			want:    []string{"ERROR 1", "f", "ERROR 2", "g", "ERROR 3 ignored", "h"},
+		},
+		{
+			name:    "context",
+			include: []string{"^ERROR"},
+			context: 1,
+			want:    []string{"b", "ERROR 1", "c", "--", "f", "ERROR 2", "g", "ERROR 3 ignored", "h"},
+		},
+		{
+			name:    "context overlapping",
+			include: []string{"^ERROR"},
+			context: 2,
+			want:    []string{"a", "b", "ERROR 1", "c", "d", "e", "f", "ERROR 2", "g", "ERROR 3 ignored", "h"},
+		},
+		{
+			name:    "excluded lines are not context",
+			include: []string{"ERROR 2"},
+			exclude: []string{"^e$"},
+			context: 3,
+			want:    []string{"f", "ERROR 2", "g", "ERROR 3 ignored", "h"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.include, tt.exclude, tt.context)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, line := range input {
+				f.Line([]byte(line), func(b []byte) {
+					got = append(got, string(b))
+				})
+			}
+
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNew_invalidPattern(t *testing.T) {
+	_, err := New(nil, []string{"["}, 0)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !strings.Contains(err.Error(), "exclude") {
+		t.Errorf("expected %q to contain %q", err.Error(), "exclude")
+	}
+}
@@ -0,0 +1,8 @@
+package follow
+
+// Followed returns the number of files being followed.
+func (m *Multi) Followed() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.readers)
+}
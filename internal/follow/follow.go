@@ -0,0 +1,483 @@
+// Package follow reads lines appended to a file, like tail -F.
+package follow
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPollInterval is how often a file is checked for new data when the
+// end of the file is reached.
+const DefaultPollInterval = 250 * time.Millisecond
+
+// Reader reads a file and the data appended to it as a single stream. It
+// keeps following the path when the file is truncated, renamed away by log
+// rotation, or removed and recreated. Read blocks until new data is
+// written, and returns io.EOF once the Reader is closed.
+type Reader struct {
+	path     string
+	interval time.Duration
+
+	mu      sync.Mutex // Protects file and removed
+	file    *os.File
+	removed bool
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// NewReader returns a Reader that starts with the last lastLines lines of
+// the file at path, at the end of the file if lastLines is 0, or at its
+// start if lastLines is negative. The file doesn't have to exist yet; it is
+// read from the start once it is created.
+func NewReader(path string, lastLines int) (*Reader, error) {
+	r := &Reader{
+		path:     path,
+		interval: DefaultPollInterval,
+		closed:   make(chan struct{}),
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := seekLastLines(f, lastLines); err != nil {
+		f.Close()
+		return nil, err
+	}
+	r.file = f
+
+	return r, nil
+}
+
+// SetPollInterval sets how often the file is checked for new data.
+func (r *Reader) SetPollInterval(d time.Duration) {
+	r.interval = d
+}
+
+func (r *Reader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.read(p)
+		if n > 0 || err != nil {
+			return n, err
+		}
+
+		select {
+		case <-r.closed:
+			return 0, io.EOF
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// read reads available data, switching to a new file at the path or
+// rewinding a truncated file when the current one has been read to the
+// end. It returns 0 and no error when there is nothing to read yet.
+func (r *Reader) read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	select {
+	case <-r.closed:
+		return 0, io.EOF
+	default:
+	}
+
+	if r.file == nil {
+		f, err := os.Open(r.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			r.removed = true
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		r.file = f
+	}
+
+	r.removed = false
+
+	n, err := r.file.Read(p)
+	if n > 0 {
+		return n, nil
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		return 0, err
+	}
+
+	// The current file has been read to the end. Check whether it is still
+	// the one at the path.
+	current, err := r.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	latest, err := os.Stat(r.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Removed or rotated, and not recreated yet. Keep reading the old
+		// file in case it is still written to.
+		r.removed = true
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if !os.SameFile(current, latest) {
+		// Rotated. Read what was written to the old file in the meantime,
+		// and then the new file from the start.
+		if n, _ := r.file.Read(p); n > 0 {
+			return n, nil
+		}
+		r.file.Close()
+		r.file = nil
+		return 0, nil
+	}
+
+	offset, err := r.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	if latest.Size() < offset {
+		// Truncated. Read again from the start.
+		if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+	}
+
+	return 0, nil
+}
+
+// drained reports whether everything has been read and the path doesn't
+// exist anymore.
+func (r *Reader) drained() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.removed
+}
+
+// Close stops following the file. A blocked Read returns io.EOF.
+func (r *Reader) Close() error {
+	r.closeOnce.Do(func() {
+		close(r.closed)
+	})
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+// seekLastLines moves the offset of f to the start of its last n lines, or
+// to its end if n is 0. It leaves the offset at the start if n is negative.
+func seekLastLines(f *os.File, n int) error {
+	if n < 0 {
+		return nil
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if n == 0 || size == 0 {
+		return nil
+	}
+
+	const chunkSize = 8192
+	buf := make([]byte, chunkSize)
+	offset := size
+	// A newline at the end of the file terminates the last line and
+	// doesn't start another one
+	skip := true
+
+	for offset > 0 {
+		readSize := min(int64(chunkSize), offset)
+		offset -= readSize
+		chunk := buf[:readSize]
+		if _, err := f.ReadAt(chunk, offset); err != nil {
+			return err
+		}
+
+		for i := len(chunk) - 1; i >= 0; i-- {
+			if chunk[i] != '\n' {
+				skip = false
+				continue
+			}
+			if skip {
+				skip = false
+				continue
+			}
+			n--
+			if n == 0 {
+				_, err := f.Seek(offset+int64(i)+1, io.SeekStart)
+				return err
+			}
+		}
+	}
+
+	// The file has fewer lines than requested
+	_, err = f.Seek(0, io.SeekStart)
+	return err
+}
+
+// Multi follows several files and merges their lines into a single stream.
+// Patterns may be globs, which are matched again periodically so that
+// files created later are followed too. Lines are never interleaved, and
+// can be prefixed with the name of their file.
+//
+// A file matching a glob is dropped once it has been read to the end and
+// no longer exists. A file that can't be read is reported to Logger and
+// dropped, and followed again from the start if it can be opened later.
+type Multi struct {
+	patterns  []string
+	lastLines int
+	prefix    bool
+	interval  time.Duration
+
+	pr *io.PipeReader
+	pw *io.PipeWriter
+
+	mu      sync.Mutex // Protects readers and failed, and serializes writes to pw
+	readers map[string]*Reader
+	// failed is the paths whose error has been reported already, until
+	// they don't match anymore
+	failed map[string]bool
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+
+	Logger *slog.Logger
+}
+
+// NewMulti returns a Multi that starts with the last lastLines lines of the
+// files matching patterns. Files matched later are read from the start.
+// If prefix is set, each line starts with the base name of its file in
+// brackets.
+func NewMulti(patterns []string, lastLines int, prefix bool, logger *slog.Logger) (*Multi, error) {
+	for _, p := range patterns {
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("incorrect value to follow option: %s: %w", p, err)
+		}
+	}
+
+	pr, pw := io.Pipe()
+	m := &Multi{
+		patterns:  patterns,
+		lastLines: lastLines,
+		prefix:    prefix,
+		interval:  DefaultPollInterval,
+		pr:        pr,
+		pw:        pw,
+		readers:   make(map[string]*Reader),
+		failed:    make(map[string]bool),
+		closed:    make(chan struct{}),
+		Logger:    logger,
+	}
+
+	if err := m.scan(lastLines, false); err != nil {
+		m.Close()
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// IsGlob reports whether pattern has any of the special characters of
+// filepath.Match.
+func IsGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// Start starts following the files. It must be called once before Read.
+func (m *Multi) Start() {
+	m.mu.Lock()
+	for path, r := range m.readers {
+		m.startReader(path, r)
+	}
+	m.mu.Unlock()
+
+	m.wg.Go(m.rescan)
+}
+
+// SetPollInterval sets how often the files are checked for new data and
+// the globs are matched again. It must be called before Start.
+func (m *Multi) SetPollInterval(d time.Duration) {
+	m.interval = d
+	for _, r := range m.readers {
+		r.SetPollInterval(d)
+	}
+}
+
+func (m *Multi) Read(p []byte) (int, error) {
+	return m.pr.Read(p)
+}
+
+// Close stops following the files. A blocked Read returns io.EOF.
+func (m *Multi) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.closed)
+
+		// Unblock the writers, which may be waiting for a read holding m.mu
+		m.pr.Close()
+
+		m.mu.Lock()
+		for _, r := range m.readers {
+			r.Close()
+		}
+		m.mu.Unlock()
+	})
+	return nil
+}
+
+// rescan matches the globs again until Close is called.
+func (m *Multi) rescan() {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.closed:
+			return
+		case <-ticker.C:
+		}
+
+		// New files are read from the start
+		if err := m.scan(-1, true); err != nil {
+			m.pw.CloseWithError(err)
+			return
+		}
+	}
+}
+
+// scan adds Readers for the paths and the files matching the globs that
+// are not followed yet, and starts them if start is set. Readers of files
+// that don't match anymore are dropped once drained. Files that can't be
+// opened make scan fail unless start is set, in which case they are
+// reported and tried again on the next scan.
+func (m *Multi) scan(lastLines int, start bool) error {
+	var paths []string
+	matched := make(map[string]bool)
+	for _, p := range m.patterns {
+		if !IsGlob(p) {
+			paths = append(paths, p)
+			matched[p] = true
+			continue
+		}
+		matches, err := filepath.Glob(p)
+		if err != nil {
+			return err
+		}
+		paths = append(paths, matches...)
+		for _, match := range matches {
+			matched[match] = true
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case <-m.closed:
+		return nil
+	default:
+	}
+
+	for path, r := range m.readers {
+		if !matched[path] && r.drained() {
+			r.Close()
+			delete(m.readers, path)
+		}
+	}
+	for path := range m.failed {
+		if !matched[path] {
+			delete(m.failed, path)
+		}
+	}
+
+	for _, path := range paths {
+		if _, ok := m.readers[path]; ok {
+			continue
+		}
+		r, err := NewReader(path, lastLines)
+		if err != nil {
+			if !start {
+				return err
+			}
+			m.reportError(path, err)
+			continue
+		}
+		r.SetPollInterval(m.interval)
+		m.readers[path] = r
+		if start {
+			m.startReader(path, r)
+		}
+	}
+
+	return nil
+}
+
+// startReader copies the lines of r to the output. m.mu must be held.
+func (m *Multi) startReader(path string, r *Reader) {
+	var prefix []byte
+	if m.prefix {
+		prefix = []byte("[" + filepath.Base(path) + "] ")
+	}
+
+	m.wg.Go(func() {
+		reader := bufio.NewReader(r)
+		for {
+			line, err := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				if line[len(line)-1] != '\n' {
+					line = append(line, '\n')
+				}
+				m.mu.Lock()
+				_, werr := m.pw.Write(append(prefix[:len(prefix):len(prefix)], line...))
+				m.mu.Unlock()
+				if werr != nil {
+					return
+				}
+			}
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				m.mu.Lock()
+				m.reportError(path, err)
+				if m.readers[path] == r {
+					delete(m.readers, path)
+				}
+				m.mu.Unlock()
+				r.Close()
+				return
+			}
+		}
+	})
+}
+
+// reportError logs err unless an error has been logged for path already.
+// m.mu must be held.
+func (m *Multi) reportError(path string, err error) {
+	if m.failed[path] {
+		return
+	}
+	m.failed[path] = true
+	m.Logger.Warn("failed to read a followed file", slog.String("path", path), slog.Any("error", err))
+}
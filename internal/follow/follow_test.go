@@ -0,0 +1,285 @@
+package follow_test
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/catatsuy/notify_slack/internal/follow"
+)
+
+// readLines sends the lines read from r to the returned channel, which is
+// closed when r returns an error.
+func readLines(r *Reader) <-chan string {
+	lines := make(chan string, 100)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	return lines
+}
+
+func expectLines(t *testing.T, lines <-chan string, want ...string) {
+	t.Helper()
+
+	for _, w := range want {
+		select {
+		case got := <-lines:
+			if got != w {
+				t.Fatalf("got %q; want %q", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %q", w)
+		}
+	}
+}
+
+func appendFile(t *testing.T, path, s string) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(s); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// lockedBuffer is a bytes.Buffer safe for concurrent use.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	appendFile(t, path, "a\nb\nc\nd\n")
+
+	r, err := NewReader(path, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.SetPollInterval(10 * time.Millisecond)
+	lines := readLines(r)
+
+	expectLines(t, lines, "c", "d")
+
+	appendFile(t, path, "e\n")
+	expectLines(t, lines, "e")
+
+	// Truncation
+	if err := os.WriteFile(path, []byte("f\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	expectLines(t, lines, "f")
+
+	// Rotation by renaming
+	appendFile(t, path, "g\n")
+	if err := os.Rename(path, path+".1"); err != nil {
+		t.Fatal(err)
+	}
+	appendFile(t, path+".1", "h\n")
+	expectLines(t, lines, "g", "h")
+	appendFile(t, path, "i\n")
+	expectLines(t, lines, "i")
+
+	// Removal and recreation
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	appendFile(t, path, "j\n")
+	expectLines(t, lines, "j")
+
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case line, ok := <-lines:
+		if ok {
+			t.Fatalf("got %q after Close", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read didn't return after Close")
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name      string
+		content   string
+		lastLines int
+		want      string
+	}{
+		{name: "end", content: "a\nb\n", lastLines: 0, want: "new"},
+		{name: "last lines", content: "a\nb\nc\n", lastLines: 2, want: "b"},
+		{name: "without a final newline", content: "a\nb\nc", lastLines: 2, want: "b"},
+		{name: "fewer lines", content: "a\nb\n", lastLines: 10, want: "a"},
+		{name: "missing file", lastLines: 10, want: "new"},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i))+".log")
+			if tt.content != "" {
+				appendFile(t, path, tt.content)
+			}
+
+			r, err := NewReader(path, tt.lastLines)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer r.Close()
+			r.SetPollInterval(10 * time.Millisecond)
+
+			if tt.content == "" || tt.lastLines == 0 {
+				appendFile(t, path, "new\n")
+			}
+			expectLines(t, readLines(r), tt.want)
+		})
+	}
+}
+
+func TestMulti(t *testing.T) {
+	dir := t.TempDir()
+	appendFile(t, filepath.Join(dir, "a.log"), "a1\na2\n")
+	appendFile(t, filepath.Join(dir, "b.txt"), "b1\n")
+
+	m, err := NewMulti([]string{filepath.Join(dir, "*.log"), filepath.Join(dir, "b.txt")}, 1, true, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetPollInterval(10 * time.Millisecond)
+	m.Start()
+
+	lines := make(chan string, 100)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(m)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+
+	// The order of the first lines of each file is not defined
+	got := map[string]bool{}
+	for range 2 {
+		select {
+		case line := <-lines:
+			got[line] = true
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out")
+		}
+	}
+	if !got["[a.log] a2"] || !got["[b.txt] b1"] {
+		t.Errorf("got %v", got)
+	}
+
+	appendFile(t, filepath.Join(dir, "b.txt"), "b2\n")
+	expectLines(t, lines, "[b.txt] b2")
+
+	// New files matching the glob are read from the start
+	appendFile(t, filepath.Join(dir, "c.log"), "c1\n")
+	expectLines(t, lines, "[c.log] c1")
+
+	m.Close()
+	select {
+	case line, ok := <-lines:
+		if ok {
+			t.Fatalf("got %q after Close", line)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Read didn't return after Close")
+	}
+}
+
+func TestMulti_removedFiles(t *testing.T) {
+	dir := t.TempDir()
+	appendFile(t, filepath.Join(dir, "a.log"), "a1\n")
+
+	logs := new(lockedBuffer)
+	m, err := NewMulti([]string{filepath.Join(dir, "*.log")}, -1, false, slog.New(slog.NewTextHandler(logs, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.SetPollInterval(10 * time.Millisecond)
+	m.Start()
+	defer m.Close()
+
+	lines := make(chan string, 100)
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(m)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+	}()
+	expectLines(t, lines, "a1")
+
+	// A removed file is dropped, and read from the start when it is
+	// created again
+	if err := os.Remove(filepath.Join(dir, "a.log")); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return m.Followed() == 0 })
+	appendFile(t, filepath.Join(dir, "a.log"), "a2\n")
+	expectLines(t, lines, "a2")
+
+	// A file that can't be read is reported once and doesn't stop the
+	// others
+	if err := os.Mkdir(filepath.Join(dir, "b.log"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, func() bool { return strings.Contains(logs.String(), "b.log") })
+	appendFile(t, filepath.Join(dir, "a.log"), "a3\n")
+	expectLines(t, lines, "a3")
+
+	time.Sleep(100 * time.Millisecond)
+	if n := strings.Count(logs.String(), "failed to read a followed file"); n != 1 {
+		t.Errorf("got %d, want %d", n, 1)
+	}
+}
+
+func TestNewMulti_invalidPattern(t *testing.T) {
+	if _, err := NewMulti([]string{"[a-"}, 0, false, slog.New(slog.NewTextHandler(io.Discard, nil))); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,183 @@
+// Package redact removes secrets from text before it is sent to Slack.
+package redact
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"regexp"
+)
+
+// Replacement is the text that replaces a secret.
+const Replacement = "[REDACTED]"
+
+// builtinPatterns detect common secrets. When a pattern has a capturing
+// group, only the group is replaced so that the context, like the name of
+// the header, is kept.
+var builtinPatterns = []string{
+	// Slack tokens
+	`\bxox[abeoprs]-[0-9A-Za-z-]{10,}`,
+	`\bxapp-[0-9A-Za-z-]{10,}`,
+	// AWS access key IDs and secret access keys
+	`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`,
+	`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})`,
+	// GitHub tokens
+	`\bgh[pousr]_[0-9A-Za-z]{36,}`,
+	`\bgithub_pat_[0-9A-Za-z_]{22,}`,
+	// Authorization headers
+	`(?i)\bbearer\s+([0-9A-Za-z._~+/-]{8,}=*)`,
+}
+
+// Private key blocks span several lines, so they are handled apart from
+// the patterns.
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)
+)
+
+// Redactor replaces secrets found by the built-in detectors and by
+// user-defined regular expressions with Replacement.
+type Redactor struct {
+	patterns []*regexp.Regexp
+
+	Logger *slog.Logger
+}
+
+// New returns a Redactor that detects the built-in secrets and also
+// matches of patterns. As for the built-in patterns, only the first
+// capturing group is replaced if a pattern has one.
+func New(patterns []string, logger *slog.Logger) (*Redactor, error) {
+	r := &Redactor{Logger: logger}
+
+	for _, p := range builtinPatterns {
+		r.patterns = append(r.patterns, regexp.MustCompile(p))
+	}
+
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect value to redact patterns option: %s: %w", p, err)
+		}
+		r.patterns = append(r.patterns, re)
+	}
+
+	return r, nil
+}
+
+// Redact returns text with the secrets replaced.
+func (r *Redactor) Redact(text []byte) []byte {
+	s := r.NewStream()
+
+	var out []byte
+	for line := range bytes.Lines(text) {
+		content, newline := bytes.CutSuffix(line, []byte("\n"))
+
+		redacted, ok := s.line(content)
+		if !ok {
+			continue
+		}
+		out = append(out, redacted...)
+		if newline {
+			out = append(out, '\n')
+		}
+	}
+
+	r.logCount(s.count)
+
+	return out
+}
+
+// Stream redacts text given line by line, keeping track of private key
+// blocks across lines.
+type Stream struct {
+	r     *Redactor
+	inKey bool
+	count int
+}
+
+// NewStream returns a Stream to redact a new input.
+func (r *Redactor) NewStream() *Stream {
+	return &Stream{r: r}
+}
+
+// Line returns line with the secrets replaced. ok is false when the whole
+// line is part of a private key block and must be dropped.
+func (s *Stream) Line(line []byte) (redacted []byte, ok bool) {
+	before := s.count
+	redacted, ok = s.line(line)
+	s.r.logCount(s.count - before)
+	return redacted, ok
+}
+
+func (s *Stream) line(line []byte) ([]byte, bool) {
+	if s.inKey {
+		loc := privateKeyEnd.FindIndex(line)
+		if loc == nil {
+			return nil, false
+		}
+		s.inKey = false
+		line = line[loc[1]:]
+		if len(bytes.TrimSpace(line)) == 0 {
+			return nil, false
+		}
+	}
+
+	// A private key block is replaced as a whole by a single Replacement
+	// on the line where it begins
+	var out []byte
+	for {
+		loc := privateKeyBegin.FindIndex(line)
+		if loc == nil {
+			break
+		}
+		out = append(out, s.replaceAll(line[:loc[0]])...)
+		out = append(out, Replacement...)
+		s.count++
+
+		rest := line[loc[1]:]
+		end := privateKeyEnd.FindIndex(rest)
+		if end == nil {
+			s.inKey = true
+			return out, true
+		}
+		line = rest[end[1]:]
+	}
+
+	return append(out, s.replaceAll(line)...), true
+}
+
+func (s *Stream) replaceAll(text []byte) []byte {
+	for _, re := range s.r.patterns {
+		text = s.replace(re, text)
+	}
+	return text
+}
+
+// replace replaces the matches of re in text, or their first capturing
+// group if re has one.
+func (s *Stream) replace(re *regexp.Regexp, text []byte) []byte {
+	matches := re.FindAllSubmatchIndex(text, -1)
+	if matches == nil {
+		return text
+	}
+
+	var out []byte
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) > 2 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		out = append(out, text[last:start]...)
+		out = append(out, Replacement...)
+		last = end
+		s.count++
+	}
+	return append(out, text[last:]...)
+}
+
+func (r *Redactor) logCount(n int) {
+	if n > 0 && r.Logger != nil {
+		r.Logger.Debug("redacted secrets", slog.Int("count", n))
+	}
+}
@@ -0,0 +1,156 @@
+package relay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/catatsuy/notify_slack/internal/slack"
+)
+
+// ErrUnsupported is returned for the methods that need the ts of a message,
+// which the relay doesn't return since it batches text.
+var ErrUnsupported = errors.New("not supported through the relay")
+
+// Client sends text and files to a relay server. It implements slack.Slack
+// so that it can replace the Slack client.
+type Client struct {
+	baseURL    string
+	HTTPClient *http.Client
+
+	Logger *slog.Logger
+}
+
+var _ slack.Slack = (*Client)(nil)
+
+// NewClient returns a Client for the server at via, either
+// unix:///path/to/socket or http://host:port.
+func NewClient(via string, logger *slog.Logger) (*Client, error) {
+	u, err := url.Parse(via)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse url: %s: %w", via, err)
+	}
+
+	c := &Client{Logger: logger}
+
+	switch u.Scheme {
+	case "unix":
+		path := u.Path
+		if path == "" {
+			path = u.Opaque
+		}
+		if path == "" {
+			return nil, fmt.Errorf("incorrect value to via option: %s: missing socket path", via)
+		}
+		transport := &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+		c.HTTPClient = &http.Client{Transport: transport}
+		// The host is ignored by the dialer
+		c.baseURL = "http://unix"
+	case "http":
+		if u.Host == "" {
+			return nil, fmt.Errorf("incorrect value to via option: %s: missing host", via)
+		}
+		c.HTTPClient = http.DefaultClient
+		c.baseURL = strings.TrimSuffix(via, "/")
+	default:
+		return nil, fmt.Errorf("incorrect value to via option: %s: unsupported scheme", via)
+	}
+
+	return c, nil
+}
+
+// PostText sends param to the server. Text without blocks or attachments
+// is posted with the next batch of its destination.
+func (c *Client) PostText(ctx context.Context, param *slack.PostTextParam) error {
+	if param.ThreadTS != "" {
+		return fmt.Errorf("thread replies are %w", ErrUnsupported)
+	}
+	if param.Text == "" && len(param.Blocks) == 0 && len(param.Attachments) == 0 {
+		return nil
+	}
+
+	b, err := json.Marshal(param)
+	if err != nil {
+		return err
+	}
+
+	return c.post(ctx, textPath, b)
+}
+
+// PostFile sends the file to the server, which uploads it to Slack. The
+// request, with the file encoded in base64, must fit in MaxPayloadBytes.
+func (c *Client) PostFile(ctx context.Context, param *slack.PostFileParam, content []byte) error {
+	b, err := json.Marshal(&FilePayload{
+		ChannelID:   param.ChannelID,
+		Filename:    param.Filename,
+		AltText:     param.AltText,
+		Title:       param.Title,
+		SnippetType: param.SnippetType,
+		Content:     content,
+
+		InitialComment: param.InitialComment,
+		ThreadTS:       param.ThreadTS,
+	})
+	if err != nil {
+		return err
+	}
+	if len(b) > MaxPayloadBytes {
+		return fmt.Errorf("%s is too large to upload through the relay: %d bytes once encoded; the relay accepts at most %d bytes", param.Filename, len(b), MaxPayloadBytes)
+	}
+
+	return c.post(ctx, filePath, b)
+}
+
+// PostMessage is not supported through the relay.
+func (c *Client) PostMessage(context.Context, *slack.PostTextParam) (*slack.ChatRes, error) {
+	return nil, fmt.Errorf("posting a message to update is %w", ErrUnsupported)
+}
+
+// UpdateMessage is not supported through the relay.
+func (c *Client) UpdateMessage(context.Context, *slack.UpdateMessageParam) error {
+	return fmt.Errorf("updating a message is %w", ErrUnsupported)
+}
+
+func (c *Client) post(ctx context.Context, path string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to connect to the relay: %w", err)
+	}
+	defer res.Body.Close()
+
+	b, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read res.Body: %w", err)
+	}
+
+	c.Logger.Debug("relay request",
+		slog.String("url", req.URL.String()),
+		slog.Int("status", res.StatusCode),
+		slog.String("body", string(b)),
+	)
+
+	if res.StatusCode != http.StatusOK && res.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to post through the relay: status: %d; body: %s", res.StatusCode, strings.TrimSpace(string(b)))
+	}
+
+	return nil
+}
@@ -0,0 +1,345 @@
+// Package relay implements a local daemon which posts messages to Slack on
+// behalf of other notify_slack processes, and the client to talk to it.
+//
+// Plain text is batched per destination through throttle.Exec, and all
+// requests to Slack share a single rate limiter, so that many short-lived
+// jobs don't exceed the rate limits of Slack.
+package relay
+
+import (
+	"context"
+	"encoding/json/v2"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/catatsuy/notify_slack/internal/redact"
+	"github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/catatsuy/notify_slack/internal/throttle"
+)
+
+const (
+	textPath = "/v1/text"
+	filePath = "/v1/file"
+
+	// MaxPayloadBytes is the maximum size of a request body.
+	MaxPayloadBytes = 64 << 20
+
+	// DefaultRateInterval is the minimum interval between requests to
+	// Slack unless configured otherwise.
+	DefaultRateInterval = time.Second
+)
+
+// FilePayload is the request body to post a file.
+type FilePayload struct {
+	ChannelID   string `json:"channel_id,omitempty"`
+	Filename    string `json:"filename"`
+	AltText     string `json:"alt_text,omitempty"`
+	Title       string `json:"title,omitempty"`
+	SnippetType string `json:"snippet_type,omitempty"`
+	Content     []byte `json:"content"`
+
+	InitialComment string `json:"initial_comment,omitempty"`
+	ThreadTS       string `json:"thread_ts,omitempty"`
+}
+
+// Server receives text and files from local clients and posts them to
+// Slack. The zero value is not usable; use NewServer.
+type Server struct {
+	slack.Slack
+
+	// Channel, Username and IconEmoji are used when a message doesn't
+	// specify them, and ChannelID when a file doesn't.
+	Channel   string
+	ChannelID string
+	Username  string
+	IconEmoji string
+
+	// Interval is how often batched text is posted.
+	Interval time.Duration
+	// MessageLimit is the maximum number of characters per message.
+	MessageLimit int
+
+	// Redactor replaces secrets in syslog messages if set. Text sent by
+	// notify_slack clients has been redacted by them.
+	Redactor *redact.Redactor
+
+	Logger *slog.Logger
+
+	limiter *limiter
+
+	mu       sync.Mutex // Protects batchers and closed
+	batchers map[destination]*batcher
+	closed   bool
+	wg       sync.WaitGroup
+}
+
+// NewServer returns a Server posting with client. Requests to Slack are
+// spaced at least rateInterval apart.
+func NewServer(client slack.Slack, rateInterval time.Duration, logger *slog.Logger) *Server {
+	return &Server{
+		Slack:        client,
+		Interval:     time.Second,
+		MessageLimit: slack.DefaultTextLimit,
+		Logger:       logger,
+		limiter:      &limiter{interval: rateInterval},
+		batchers:     make(map[destination]*batcher),
+	}
+}
+
+// Handler returns the HTTP handler of the relay API.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+textPath, s.handleText)
+	mux.HandleFunc("POST "+filePath, s.handleFile)
+	return mux
+}
+
+func (s *Server) handleText(w http.ResponseWriter, r *http.Request) {
+	var param slack.PostTextParam
+	if err := json.UnmarshalRead(http.MaxBytesReader(w, r.Body, MaxPayloadBytes), &param); err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse the request: %s", err), http.StatusBadRequest)
+		return
+	}
+	s.fillDefaults(&param)
+
+	if isWhole(&param) {
+		// The text sent to the destination before is posted first
+		s.flush(newDestination(&param))
+		if err := s.postText(r.Context(), &param); err != nil {
+			s.Logger.Warn("failed to post text", slog.String("channel", param.Channel), slog.Any("error", err))
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if err := s.enqueue(&param); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) handleFile(w http.ResponseWriter, r *http.Request) {
+	var payload FilePayload
+	if err := json.UnmarshalRead(http.MaxBytesReader(w, r.Body, MaxPayloadBytes), &payload); err != nil {
+		http.Error(w, fmt.Sprintf("failed to parse the request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	param := &slack.PostFileParam{
+		ChannelID:   payload.ChannelID,
+		Filename:    payload.Filename,
+		AltText:     payload.AltText,
+		Title:       payload.Title,
+		SnippetType: payload.SnippetType,
+
+		InitialComment: payload.InitialComment,
+		ThreadTS:       payload.ThreadTS,
+	}
+	if param.ChannelID == "" {
+		param.ChannelID = s.ChannelID
+	}
+
+	if err := s.limiter.wait(r.Context()); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	if err := s.PostFile(r.Context(), param, payload.Content); err != nil {
+		s.Logger.Warn("failed to post file", slog.String("channel_id", param.ChannelID), slog.Any("error", err))
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) fillDefaults(param *slack.PostTextParam) {
+	if param.Channel == "" {
+		param.Channel = s.Channel
+	}
+	if param.Username == "" {
+		param.Username = s.Username
+	}
+	if param.IconEmoji == "" {
+		param.IconEmoji = s.IconEmoji
+	}
+}
+
+func (s *Server) postText(ctx context.Context, param *slack.PostTextParam) error {
+	if err := s.limiter.wait(ctx); err != nil {
+		return err
+	}
+	return s.PostText(ctx, param)
+}
+
+// isWhole reports whether param must be posted as is instead of being
+// merged with other text. Messages with blocks or attachments can't be
+// merged, and text in a code block could be cut inside it when the merged
+// text is split.
+func isWhole(param *slack.PostTextParam) bool {
+	return len(param.Blocks) > 0 || len(param.Attachments) > 0 || strings.Contains(param.Text, "```")
+}
+
+// enqueue adds the text of param to the batch of its destination.
+func (s *Server) enqueue(param *slack.PostTextParam) error {
+	if param.Text == "" {
+		return nil
+	}
+
+	dest := newDestination(param)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return errors.New("the server is shutting down")
+	}
+
+	b, ok := s.batchers[dest]
+	if !ok {
+		b = s.startBatcher(dest)
+		s.batchers[dest] = b
+	}
+
+	text := param.Text
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	// Writes to the pipe are serialized, so that texts are not interleaved
+	_, err := io.WriteString(b.pw, text)
+	return err
+}
+
+// flush posts the text batched for dest, and waits until it is posted.
+// Text sent to dest afterwards starts a new batch.
+func (s *Server) flush(dest destination) {
+	s.mu.Lock()
+	b, ok := s.batchers[dest]
+	if ok {
+		delete(s.batchers, dest)
+		b.pw.Close()
+	}
+	s.mu.Unlock()
+
+	if ok {
+		<-b.done
+	}
+}
+
+// Close posts the batched text and stops the batchers. The HTTP servers
+// must have been shut down before.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.closed = true
+	for _, b := range s.batchers {
+		b.pw.Close()
+	}
+	s.mu.Unlock()
+
+	s.wg.Wait()
+	return nil
+}
+
+// destination identifies the messages that can be merged.
+type destination struct {
+	channel   string
+	username  string
+	iconEmoji string
+}
+
+func newDestination(param *slack.PostTextParam) destination {
+	return destination{
+		channel:   param.Channel,
+		username:  param.Username,
+		iconEmoji: param.IconEmoji,
+	}
+}
+
+// batcher merges the text sent to a destination with throttle.Exec.
+type batcher struct {
+	pw *io.PipeWriter
+	// done is closed once the text written to pw is posted
+	done chan struct{}
+}
+
+// startBatcher starts a batcher for dest. s.mu must be held.
+func (s *Server) startBatcher(dest destination) *batcher {
+	pr, pw := io.Pipe()
+	b := &batcher{pw: pw, done: make(chan struct{})}
+
+	base := slack.PostTextParam{
+		Channel:   dest.channel,
+		Username:  dest.username,
+		IconEmoji: dest.iconEmoji,
+	}
+	post := func(ctx context.Context, output string) error {
+		if output == "" {
+			return nil
+		}
+		for _, text := range slack.SplitText(output, s.MessageLimit) {
+			param := base
+			param.Text = text
+			if err := s.postText(ctx, &param); err != nil {
+				s.Logger.Warn("failed to post text", slog.String("channel", dest.channel), slog.Any("error", err))
+				return err
+			}
+		}
+		return nil
+	}
+
+	ex := throttle.NewExec(pr)
+
+	s.wg.Go(func() {
+		defer close(b.done)
+
+		var interval <-chan time.Time
+		if s.Interval > 0 {
+			ticker := time.NewTicker(s.Interval)
+			defer ticker.Stop()
+			interval = ticker.C
+		}
+		// Errors are logged by post. The batcher keeps running.
+		ex.Start(context.Background(), interval, post, post)
+	})
+
+	return b
+}
+
+// limiter spaces requests at least interval apart.
+type limiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+func (l *limiter) wait(ctx context.Context) error {
+	l.mu.Lock()
+	now := time.Now()
+	at := now
+	if l.next.After(now) {
+		at = l.next
+	}
+	l.next = at.Add(l.interval)
+	l.mu.Unlock()
+
+	d := at.Sub(now)
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
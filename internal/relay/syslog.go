@@ -0,0 +1,107 @@
+package relay
+
+import (
+	"errors"
+	"log/slog"
+	"net"
+	"strings"
+
+	"github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/catatsuy/notify_slack/internal/syslog"
+)
+
+// maxSyslogPacket is the size of the largest UDP datagram.
+const maxSyslogPacket = 64 * 1024
+
+// severityEmoji marks the lines of the severities that need attention.
+var severityEmoji = map[syslog.Severity]string{
+	syslog.Emergency: ":rotating_light:",
+	syslog.Alert:     ":rotating_light:",
+	syslog.Critical:  ":rotating_light:",
+	syslog.Error:     ":red_circle:",
+	syslog.Warning:   ":warning:",
+}
+
+// SyslogInput is where syslog messages are posted, and which of them.
+type SyslogInput struct {
+	// Channel overrides the default channel of the server if set
+	Channel string
+	// MinSeverity is the least severe severity posted
+	MinSeverity syslog.Severity
+}
+
+// ServeSyslog reads syslog messages from conn, one per datagram, and
+// batches them with the text of the same destination until conn is closed.
+// conn must be closed before Close is called.
+func (s *Server) ServeSyslog(conn net.PacketConn, input SyslogInput) error {
+	buf := make([]byte, maxSyslogPacket)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+
+		m, err := syslog.Parse(buf[:n])
+		if err != nil {
+			s.Logger.Debug("ignored a syslog message", slog.String("from", addr.String()), slog.Any("error", err))
+			continue
+		}
+		if m.Severity > input.MinSeverity {
+			continue
+		}
+		if s.Redactor != nil {
+			m.Content = string(s.Redactor.Redact([]byte(m.Content)))
+		}
+
+		param := &slack.PostTextParam{
+			Channel: input.Channel,
+			Text:    FormatSyslog(m),
+		}
+		s.fillDefaults(param)
+		if err := s.enqueue(param); err != nil {
+			s.Logger.Warn("failed to queue a syslog message", slog.String("from", addr.String()), slog.Any("error", err))
+		}
+	}
+}
+
+// FormatSyslog returns the line posted for m, like
+// "[info] web1 nginx[42]: started". Messages of warning or more severe start
+// with an emoji and their severity in bold. The fields of m are escaped so
+// that they can't mention people or break the formatting.
+func FormatSyslog(m *syslog.Message) string {
+	var b strings.Builder
+
+	if emoji, ok := severityEmoji[m.Severity]; ok {
+		b.WriteString(emoji)
+		b.WriteString(" *[")
+		b.WriteString(m.Severity.String())
+		b.WriteString("]* ")
+	} else {
+		b.WriteString("[")
+		b.WriteString(m.Severity.String())
+		b.WriteString("] ")
+	}
+
+	if m.Hostname != "" {
+		b.WriteString(slack.EscapeText(m.Hostname))
+		b.WriteString(" ")
+	}
+	if m.AppName != "" {
+		b.WriteString(slack.EscapeText(m.AppName))
+		if m.ProcID != "" {
+			b.WriteString("[")
+			b.WriteString(slack.EscapeText(m.ProcID))
+			b.WriteString("]")
+		}
+		b.WriteString(": ")
+	}
+
+	// A message is posted as a single line
+	content := strings.ReplaceAll(strings.TrimRight(m.Content, "\n"), "\n", " ")
+	b.WriteString(slack.EscapeText(content))
+
+	return b.String()
+}
@@ -0,0 +1,63 @@
+package slack
+
+// Block is a Block Kit layout block. Only the fields used by notify_slack
+// are modeled.
+//
+// https://api.slack.com/reference/block-kit/blocks
+type Block struct {
+	Type     string        `json:"type"`
+	Text     *TextObject   `json:"text,omitempty"`
+	Fields   []*TextObject `json:"fields,omitempty"`
+	Elements []*TextObject `json:"elements,omitempty"`
+}
+
+// TextObject is a plain_text or mrkdwn composition object.
+type TextObject struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+// Attachment is a secondary attachment. notify_slack uses it to show
+// blocks with a colored bar on the side.
+//
+// https://api.slack.com/reference/messaging/attachments
+type Attachment struct {
+	Color    string  `json:"color,omitempty"`
+	Fallback string  `json:"fallback,omitempty"`
+	Text     string  `json:"text,omitempty"`
+	Blocks   []Block `json:"blocks,omitempty"`
+}
+
+const (
+	ColorGood   = "good"
+	ColorDanger = "danger"
+)
+
+// Limits of the text of blocks. Slack rejects blocks with longer text.
+const (
+	MaxHeaderTextLength  = 150
+	MaxSectionTextLength = 3000
+	MaxFieldTextLength   = 2000
+)
+
+func NewPlainText(text string) *TextObject {
+	return &TextObject{Type: "plain_text", Text: text}
+}
+
+func NewMrkdwn(text string) *TextObject {
+	return &TextObject{Type: "mrkdwn", Text: text}
+}
+
+func NewHeaderBlock(text string) Block {
+	return Block{Type: "header", Text: NewPlainText(text)}
+}
+
+// NewSectionBlock returns a section block showing text and, if any, fields
+// in two columns.
+func NewSectionBlock(text *TextObject, fields ...*TextObject) Block {
+	return Block{Type: "section", Text: text, Fields: fields}
+}
+
+func NewContextBlock(elements ...*TextObject) Block {
+	return Block{Type: "context", Elements: elements}
+}
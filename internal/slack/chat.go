@@ -0,0 +1,102 @@
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json/v2"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+var (
+	chatPostMessageURL = "https://slack.com/api/chat.postMessage"
+	chatUpdateURL      = "https://slack.com/api/chat.update"
+)
+
+// ChatRes is the response of chat.postMessage and chat.update.
+type ChatRes struct {
+	OK      bool   `json:"ok"`
+	Error   string `json:"error,omitempty"`
+	Channel string `json:"channel,omitempty"`
+	TS      string `json:"ts,omitempty"`
+}
+
+type UpdateMessageParam struct {
+	// Channel must be a channel ID, such as the one returned by PostMessage.
+	Channel string `json:"channel"`
+	TS      string `json:"ts"`
+	Text    string `json:"text"`
+}
+
+// PostMessage posts a message with chat.postMessage using the token.
+// The returned ChatRes holds the channel ID and the ts of the message,
+// which identify it for UpdateMessage.
+func (c *Client) PostMessage(ctx context.Context, param *PostTextParam) (*ChatRes, error) {
+	if param.Channel == "" {
+		return nil, fmt.Errorf("provide channel")
+	}
+
+	b, err := json.Marshal(param)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.callChatAPI(ctx, chatPostMessageURL, b)
+}
+
+// UpdateMessage rewrites the text of a message posted by PostMessage with
+// chat.update.
+func (c *Client) UpdateMessage(ctx context.Context, param *UpdateMessageParam) error {
+	if param.Channel == "" || param.TS == "" {
+		return fmt.Errorf("provide channel and ts")
+	}
+
+	b, err := json.Marshal(param)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.callChatAPI(ctx, chatUpdateURL, b)
+	return err
+}
+
+func (c *Client) callChatAPI(ctx context.Context, apiURL string, reqBody []byte) (*ChatRes, error) {
+	res, b, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, apiURL, bytes.NewReader(reqBody))
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
+
+		return req, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	c.Logger.Debug("request",
+		slog.String("url", res.Request.URL.String()),
+		slog.String("method", res.Request.Method),
+		slog.Int("status", res.StatusCode),
+		slog.String("body", string(b)),
+	)
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to read res.Body and the status code: %d; body: %s", res.StatusCode, b)
+	}
+
+	apiRes := &ChatRes{}
+	err = json.Unmarshal(b, apiRes)
+	if err != nil {
+		return nil, fmt.Errorf("response returned from slack is not json: body: %s: %w", b, err)
+	}
+
+	if !apiRes.OK {
+		return nil, fmt.Errorf("response has failed: %s; body: %s", apiRes.Error, b)
+	}
+
+	return apiRes, nil
+}
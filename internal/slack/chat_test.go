@@ -0,0 +1,165 @@
+package slack_test
+
+import (
+	"encoding/json/v2"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	. "github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/google/go-cmp/cmp"
+)
+
+func assertChatAPIRequest(t *testing.T, r *http.Request, token string) map[string]any {
+	t.Helper()
+
+	assertSlackAPIRequest(t, r)
+
+	contentType := r.Header.Get("Content-Type")
+	expectedType := "application/json; charset=utf-8"
+	if contentType != expectedType {
+		t.Fatalf("Content-Type expected %s, but %s", expectedType, contentType)
+	}
+
+	authorization := r.Header.Get("Authorization")
+	expectedAuth := "Bearer " + token
+	if authorization != expectedAuth {
+		t.Fatalf("Authorization expected %s, but %s", expectedAuth, authorization)
+	}
+
+	bodyBytes, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Body.Close()
+
+	actualBody := map[string]any{}
+	err = json.Unmarshal(bodyBytes, &actualBody)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return actualBody
+}
+
+func TestPostMessage_Success(t *testing.T) {
+	muxAPI := http.NewServeMux()
+	testAPIServer := httptest.NewTestServer(t, muxAPI)
+	testHTTPClient := testAPIServer.Client()
+
+	slackToken := "slack-token"
+
+	muxAPI.HandleFunc("POST slack.com/api/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		actualBody := assertChatAPIRequest(t, r, slackToken)
+
+		expectedBody := map[string]any{
+			"channel":    "#test",
+			"username":   "tester",
+			"text":       "testtesttest",
+			"icon_emoji": ":rocket:",
+		}
+		if diff := cmp.Diff(expectedBody, actualBody); diff != "" {
+			t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+		}
+
+		http.ServeFile(w, r, "testdata/chat_post_message_ok.json")
+	})
+
+	c, err := NewClientForPostFile(slackToken, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HTTPClient = testHTTPClient
+
+	res, err := c.PostMessage(t.Context(), &PostTextParam{
+		Channel:   "#test",
+		Username:  "tester",
+		Text:      "testtesttest",
+		IconEmoji: ":rocket:",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Channel != "C123ABC456" {
+		t.Errorf("expected %q to equal %q", res.Channel, "C123ABC456")
+	}
+	if res.TS != "1503435956.000247" {
+		t.Errorf("expected %q to equal %q", res.TS, "1503435956.000247")
+	}
+}
+
+func TestPostMessage_Fail(t *testing.T) {
+	muxAPI := http.NewServeMux()
+	testAPIServer := httptest.NewTestServer(t, muxAPI)
+	testHTTPClient := testAPIServer.Client()
+
+	muxAPI.HandleFunc("POST slack.com/api/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/chat_post_message_fail.json")
+	})
+
+	c, err := NewClientForPostFile("slack-token", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HTTPClient = testHTTPClient
+
+	_, err = c.PostMessage(t.Context(), &PostTextParam{Channel: "#nothing", Text: "testtesttest"})
+	if err == nil {
+		t.Fatal("expected error, but nothing was returned")
+	}
+
+	expected := "channel_not_found"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected %q to contain %q", err.Error(), expected)
+	}
+
+	_, err = c.PostMessage(t.Context(), &PostTextParam{Text: "testtesttest"})
+	expected = "provide channel"
+	if err == nil {
+		t.Fatal("expected error, but nothing was returned")
+	} else if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected %q to contain %q", err.Error(), expected)
+	}
+}
+
+func TestUpdateMessage_Success(t *testing.T) {
+	muxAPI := http.NewServeMux()
+	testAPIServer := httptest.NewTestServer(t, muxAPI)
+	testHTTPClient := testAPIServer.Client()
+
+	slackToken := "slack-token"
+
+	muxAPI.HandleFunc("POST slack.com/api/chat.update", func(w http.ResponseWriter, r *http.Request) {
+		actualBody := assertChatAPIRequest(t, r, slackToken)
+
+		expectedBody := map[string]any{
+			"channel": "C123ABC456",
+			"ts":      "1503435956.000247",
+			"text":    "updated",
+		}
+		if diff := cmp.Diff(expectedBody, actualBody); diff != "" {
+			t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+		}
+
+		http.ServeFile(w, r, "testdata/chat_update_ok.json")
+	})
+
+	c, err := NewClientForPostFile(slackToken, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HTTPClient = testHTTPClient
+
+	err = c.UpdateMessage(t.Context(), &UpdateMessageParam{
+		Channel: "C123ABC456",
+		TS:      "1503435956.000247",
+		Text:    "updated",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -27,6 +27,8 @@ type Client struct {
 
 	Token string
 
+	Retry RetryPolicy
+
 	Logger *slog.Logger
 }
 
@@ -41,6 +43,16 @@ type PostTextParam struct {
 	Username  string `json:"username,omitempty"`
 	Text      string `json:"text"`
 	IconEmoji string `json:"icon_emoji,omitempty"`
+
+	// Text is used as the fallback for notifications when Blocks or
+	// Attachments are given.
+	Blocks      []Block      `json:"blocks,omitempty"`
+	Attachments []Attachment `json:"attachments,omitempty"`
+
+	// ThreadTS and ReplyBroadcast are only supported by PostMessage.
+	// Incoming Webhooks can't post into a thread.
+	ThreadTS       string `json:"thread_ts,omitempty"`
+	ReplyBroadcast bool   `json:"reply_broadcast,omitzero"`
 }
 
 type PostFileParam struct {
@@ -49,6 +61,11 @@ type PostFileParam struct {
 	AltText     string
 	Title       string
 	SnippetType string
+
+	// InitialComment is posted with the file, and ThreadTS posts the file
+	// as a reply in a thread. Both need ChannelID.
+	InitialComment string
+	ThreadTS       string
 }
 
 type GetUploadURLExternalResParam struct {
@@ -61,6 +78,8 @@ type GetUploadURLExternalResParam struct {
 type Slack interface {
 	PostText(ctx context.Context, param *PostTextParam) error
 	PostFile(ctx context.Context, param *PostFileParam, content []byte) error
+	PostMessage(ctx context.Context, param *PostTextParam) (*ChatRes, error)
+	UpdateMessage(ctx context.Context, param *UpdateMessageParam) error
 }
 
 func NewClient(urlStr string, logger *slog.Logger) (*Client, error) {
@@ -76,12 +95,16 @@ func NewClient(urlStr string, logger *slog.Logger) (*Client, error) {
 	client := &Client{
 		URL:        parsedURL,
 		HTTPClient: http.DefaultClient,
+		Retry:      DefaultRetryPolicy(),
 		Logger:     logger,
 	}
 
 	return client, nil
 }
 
+// NewClientForPostFile creates a client for the Slack Web API authenticated
+// with token. Since it has no Incoming Webhooks URL, PostText posts messages
+// with chat.postMessage.
 func NewClientForPostFile(token string, logger *slog.Logger) (*Client, error) {
 	if len(token) == 0 {
 		return nil, fmt.Errorf("provide Slack token")
@@ -90,58 +113,69 @@ func NewClientForPostFile(token string, logger *slog.Logger) (*Client, error) {
 	client := &Client{
 		HTTPClient: http.DefaultClient,
 		Token:      token,
+		Retry:      DefaultRetryPolicy(),
 		Logger:     logger,
 	}
 
 	return client, nil
 }
 
-func (c *Client) newRequest(ctx context.Context, method string, body io.Reader) (*http.Request, error) {
+func (c *Client) newRequest(method string, body io.Reader) (*http.Request, error) {
 	u := *c.URL
 
-	req, err := http.NewRequest(method, u.String(), body)
+	return http.NewRequest(method, u.String(), body)
+}
+
+// newFormRequest builds a form-encoded request to the Slack Web API
+// authenticated with the token.
+func (c *Client) newFormRequest(apiURL string, v url.Values) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, apiURL, strings.NewReader(v.Encode()))
 	if err != nil {
 		return nil, err
 	}
 
-	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
 
 	return req, nil
 }
 
+// PostText posts a message to the Incoming Webhooks URL, or with
+// chat.postMessage when the client was created with a token only.
 func (c *Client) PostText(ctx context.Context, param *PostTextParam) error {
-	if param.Text == "" {
+	if param.Text == "" && len(param.Blocks) == 0 && len(param.Attachments) == 0 {
 		return nil
 	}
 
-	b, _ := json.Marshal(param)
-
-	req, err := c.newRequest(ctx, http.MethodPost, bytes.NewBuffer(b))
-	if err != nil {
+	if c.URL == nil {
+		_, err := c.PostMessage(ctx, param)
 		return err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	b, _ := json.Marshal(param)
+
+	res, body, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := c.newRequest(http.MethodPost, bytes.NewReader(b))
+		if err != nil {
+			return nil, err
+		}
 
-	c.Logger.Debug("request",
-		slog.String("url", req.URL.String()),
-		slog.String("method", req.Method),
-	)
+		req.Header.Set("Content-Type", "application/json")
 
-	res, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer res.Body.Close()
+		c.Logger.Debug("request",
+			slog.String("url", req.URL.String()),
+			slog.String("method", req.Method),
+		)
 
-	body, err := io.ReadAll(res.Body)
+		return req, nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to read res.Body: %w", err)
+		return err
 	}
 
 	c.Logger.Debug("request",
-		slog.String("url", req.URL.String()),
-		slog.String("method", req.Method),
+		slog.String("url", res.Request.URL.String()),
+		slog.String("method", res.Request.Method),
 		slog.Int("status", res.StatusCode),
 		slog.String("body", string(body)),
 	)
@@ -172,9 +206,11 @@ func (c *Client) PostFile(ctx context.Context, param *PostFileParam, content []b
 	}
 
 	cParam := &CompleteUploadExternalParam{
-		FileID:    fileID,
-		Title:     param.Title,
-		ChannelID: param.ChannelID,
+		FileID:         fileID,
+		Title:          param.Title,
+		ChannelID:      param.ChannelID,
+		InitialComment: param.InitialComment,
+		ThreadTS:       param.ThreadTS,
 	}
 
 	err = c.CompleteUploadExternal(ctx, cParam)
@@ -210,26 +246,12 @@ func (c *Client) GetUploadURLExternalURL(ctx context.Context, param *GetUploadUR
 		v.Set("snippet_type", param.SnippetType)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, filesGetUploadURLExternalURL, strings.NewReader(v.Encode()))
-	if err != nil {
-		return "", "", err
-	}
-
-	req = req.WithContext(ctx)
-
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
-
-	res, err := c.HTTPClient.Do(req)
+	res, b, err := c.do(ctx, func() (*http.Request, error) {
+		return c.newFormRequest(filesGetUploadURLExternalURL, v)
+	})
 	if err != nil {
 		return "", "", err
 	}
-	defer res.Body.Close()
-
-	b, err := io.ReadAll(res.Body)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to read res.Body: %w", err)
-	}
 
 	if res.StatusCode != http.StatusOK {
 		return "", "", fmt.Errorf("failed to read res.Body and the status code: %d; body: %s", res.StatusCode, b)
@@ -268,25 +290,19 @@ func (c *Client) UploadToURL(ctx context.Context, filename, uploadURL string, co
 		return fmt.Errorf("failed to close writer: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, uploadURL, body)
-	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req = req.WithContext(ctx)
+	res, b, err := c.do(ctx, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodPost, uploadURL, bytes.NewReader(body.Bytes()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
 
-	req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Content-Type", contentType)
 
-	res, err := c.HTTPClient.Do(req)
+		return req, nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to do request: %w", err)
 	}
-	defer res.Body.Close()
-
-	b, err := io.ReadAll(res.Body)
-	if err != nil {
-		return fmt.Errorf("failed to read res.Body: %w", err)
-	}
 
 	if res.StatusCode != http.StatusOK {
 		return fmt.Errorf("failed to read res.Body and the status code: %d; body: %s", res.StatusCode, b)
@@ -312,6 +328,9 @@ type CompleteUploadExternalParam struct {
 	FileID    string
 	Title     string
 	ChannelID string
+
+	InitialComment string
+	ThreadTS       string
 }
 
 func (c *Client) CompleteUploadExternal(ctx context.Context, params *CompleteUploadExternalParam) error {
@@ -326,31 +345,23 @@ func (c *Client) CompleteUploadExternal(ctx context.Context, params *CompleteUpl
 	if params.ChannelID != "" {
 		v.Set("channel_id", params.ChannelID)
 	}
-
-	req, err := http.NewRequest(http.MethodPost, filesCompleteUploadExternalURL, strings.NewReader(v.Encode()))
-	if err != nil {
-		return err
+	if params.InitialComment != "" {
+		v.Set("initial_comment", params.InitialComment)
 	}
-
-	req = req.WithContext(ctx)
-
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.Token))
-
-	res, err := c.HTTPClient.Do(req)
-	if err != nil {
-		return err
+	if params.ThreadTS != "" {
+		v.Set("thread_ts", params.ThreadTS)
 	}
-	defer res.Body.Close()
 
-	b, err := io.ReadAll(res.Body)
+	res, b, err := c.do(ctx, func() (*http.Request, error) {
+		return c.newFormRequest(filesCompleteUploadExternalURL, v)
+	})
 	if err != nil {
-		return fmt.Errorf("failed to read res.Body: %w", err)
+		return err
 	}
 
 	c.Logger.Debug("request",
-		slog.String("url", req.URL.String()),
-		slog.String("method", req.Method),
+		slog.String("url", res.Request.URL.String()),
+		slog.String("method", res.Request.Method),
 		slog.Int("status", res.StatusCode),
 		slog.String("body", string(b)),
 	)
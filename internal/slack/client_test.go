@@ -88,7 +88,7 @@ func TestPostText_Success(t *testing.T) {
 		}
 
 		if !reflect.DeepEqual(actualBody, param) {
-			t.Fatalf("expected %q to equal %q", actualBody, param)
+			t.Fatalf("expected %+v to equal %+v", actualBody, param)
 		}
 
 		http.ServeFile(w, r, "testdata/post_text_ok.html")
@@ -107,6 +107,46 @@ func TestPostText_Success(t *testing.T) {
 	}
 }
 
+func TestPostText_empty(t *testing.T) {
+	muxAPI := http.NewServeMux()
+	testAPIServer := httptest.NewTestServer(t, muxAPI)
+	testHTTPClient := testAPIServer.Client()
+
+	var texts []string
+	muxAPI.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		actualBody := &PostTextParam{}
+		if err := json.UnmarshalRead(r.Body, actualBody); err != nil {
+			t.Fatal(err)
+		}
+		texts = append(texts, actualBody.Attachments[0].Text)
+
+		http.ServeFile(w, r, "testdata/post_text_ok.html")
+	})
+
+	c, err := NewClient(testAPIServer.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HTTPClient = testHTTPClient
+
+	// Nothing to post
+	if err := c.PostText(t.Context(), &PostTextParam{}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Attachments are posted without text
+	param := &PostTextParam{
+		Attachments: []Attachment{{Color: ColorDanger, Text: "failed"}},
+	}
+	if err := c.PostText(t.Context(), param); err != nil {
+		t.Fatal(err)
+	}
+
+	if diff := cmp.Diff([]string{"failed"}, texts); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+}
+
 func TestPostText_Fail(t *testing.T) {
 	muxAPI := http.NewServeMux()
 	testAPIServer := httptest.NewTestServer(t, muxAPI)
@@ -147,6 +187,86 @@ func TestPostText_Fail(t *testing.T) {
 	}
 }
 
+func TestPostText_Token(t *testing.T) {
+	muxAPI := http.NewServeMux()
+	testAPIServer := httptest.NewTestServer(t, muxAPI)
+	testHTTPClient := testAPIServer.Client()
+
+	slackToken := "slack-token"
+
+	param := &PostTextParam{
+		Channel:   "C12345678",
+		Username:  "tester",
+		Text:      "testtesttest",
+		IconEmoji: ":rocket:",
+	}
+
+	muxAPI.HandleFunc("POST slack.com/api/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		assertSlackAPIRequest(t, r)
+
+		authorization := r.Header.Get("Authorization")
+		expectedAuth := "Bearer " + slackToken
+		if authorization != expectedAuth {
+			t.Fatalf("Authorization expected %s, but %s", expectedAuth, authorization)
+		}
+
+		bodyBytes, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+
+		actualBody := &PostTextParam{}
+		err = json.Unmarshal(bodyBytes, actualBody)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if diff := cmp.Diff(param, actualBody); diff != "" {
+			t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+		}
+
+		http.ServeFile(w, r, "testdata/chat_post_message_ok.json")
+	})
+
+	c, err := NewClientForPostFile(slackToken, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HTTPClient = testHTTPClient
+
+	err = c.PostText(t.Context(), param)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPostText_TokenFail(t *testing.T) {
+	muxAPI := http.NewServeMux()
+	testAPIServer := httptest.NewTestServer(t, muxAPI)
+	testHTTPClient := testAPIServer.Client()
+
+	muxAPI.HandleFunc("POST slack.com/api/chat.postMessage", func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, "testdata/chat_post_message_fail.json")
+	})
+
+	c, err := NewClientForPostFile("slack-token", slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HTTPClient = testHTTPClient
+
+	err = c.PostText(t.Context(), &PostTextParam{Channel: "#nothing", Text: "testtesttest"})
+	if err == nil {
+		t.Fatal("expected error, but nothing was returned")
+	}
+
+	expected := "response has failed: channel_not_found"
+	if !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected %q to contain %q", err.Error(), expected)
+	}
+}
+
 func TestPostFile_Success(t *testing.T) {
 	muxAPI := http.NewServeMux()
 	testAPIServer := httptest.NewTestServer(t, muxAPI)
@@ -641,3 +761,69 @@ func TestCompleteUploadExternal_Success(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestCompleteUploadExternal_InitialComment(t *testing.T) {
+	muxAPI := http.NewServeMux()
+	testAPIServer := httptest.NewTestServer(t, muxAPI)
+	testHTTPClient := testAPIServer.Client()
+
+	slackToken := "slack-token"
+
+	muxAPI.HandleFunc("POST slack.com/api/files.completeUploadExternal", func(w http.ResponseWriter, r *http.Request) {
+		assertSlackAPIRequest(t, r)
+
+		contentType := r.Header.Get("Content-Type")
+		expectedType := "application/x-www-form-urlencoded"
+		if contentType != expectedType {
+			t.Fatalf("Content-Type expected %s, but %s", expectedType, contentType)
+		}
+
+		authorization := r.Header.Get("Authorization")
+		expectedAuth := "Bearer " + slackToken
+		if authorization != expectedAuth {
+			t.Fatalf("Authorization expected %s, but %s", expectedAuth, authorization)
+		}
+
+		bodyBytes, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Body.Close()
+
+		actualV, err := url.ParseQuery(string(bodyBytes))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expectedV := url.Values{}
+		expectedV.Set("files", `[{"id":"file-id","title":"file-title"}]`)
+		expectedV.Set("channel_id", "C0NF841BK")
+		expectedV.Set("initial_comment", "full output")
+		expectedV.Set("thread_ts", "1700000000.000100")
+
+		if diff := cmp.Diff(expectedV, actualV); diff != "" {
+			t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+		}
+
+		http.ServeFile(w, r, "testdata/files_complete_upload_external_ok.json")
+	})
+
+	c, err := NewClientForPostFile(slackToken, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HTTPClient = testHTTPClient
+
+	param := &CompleteUploadExternalParam{
+		FileID:    "file-id",
+		Title:     "file-title",
+		ChannelID: "C0NF841BK",
+
+		InitialComment: "full output",
+		ThreadTS:       "1700000000.000100",
+	}
+	err = c.CompleteUploadExternal(t.Context(), param)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
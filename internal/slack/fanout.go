@@ -0,0 +1,170 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+)
+
+// DefaultFanOutQueueSize is how many texts can wait for a destination
+// unless configured otherwise. It holds the texts flushed every second
+// while a request is retried for the default retry budget.
+const DefaultFanOutQueueSize = 256
+
+// Destination is a Slack client with a name to tell it apart in errors.
+type Destination struct {
+	Name string
+	// Channel replaces the channel of the text posted to this destination
+	// if set.
+	Channel string
+	Slack
+}
+
+// FanOut posts text to several destinations at the same time. Each
+// destination has its own queue, so that a slow, retrying or failing
+// destination doesn't hold back the others. Failures of a destination are
+// logged as they happen and returned by Close, so that PostText never
+// fails because of another destination. When QueueSize texts are waiting
+// for a destination, newer texts to it are dropped.
+//
+// Files are uploaded once, with the first destination. Messages can't be
+// updated since each destination has its own ts.
+type FanOut struct {
+	Destinations []Destination
+	// QueueSize is how many texts can wait for each destination. It must
+	// be set before the first call to PostText.
+	QueueSize int
+
+	Logger *slog.Logger
+
+	startOnce sync.Once
+	closeOnce sync.Once
+	queues    []*fanOutQueue
+	wg        sync.WaitGroup
+}
+
+// fanOutQueue holds the texts waiting for a destination and the outcome of
+// the texts posted to it.
+type fanOutQueue struct {
+	texts chan fanOutText
+
+	mu      sync.Mutex // Protects the fields below
+	posts   int
+	failed  int
+	dropped int
+	err     error // the latest error
+}
+
+// fanOutText is a text waiting in the queue of a destination.
+type fanOutText struct {
+	ctx   context.Context
+	param PostTextParam
+}
+
+func NewFanOut(destinations []Destination, logger *slog.Logger) *FanOut {
+	return &FanOut{
+		Destinations: destinations,
+		QueueSize:    DefaultFanOutQueueSize,
+		Logger:       logger,
+	}
+}
+
+// start starts posting the queued texts of each destination.
+func (f *FanOut) start() {
+	f.startOnce.Do(func() {
+		f.queues = make([]*fanOutQueue, len(f.Destinations))
+		for i, d := range f.Destinations {
+			q := &fanOutQueue{texts: make(chan fanOutText, f.QueueSize)}
+			f.queues[i] = q
+			f.wg.Go(func() {
+				for t := range q.texts {
+					err := d.PostText(t.ctx, &t.param)
+					if err != nil {
+						f.Logger.Warn("failed to post text", slog.String("destination", d.Name), slog.Any("error", err))
+					}
+
+					q.mu.Lock()
+					q.posts++
+					if err != nil {
+						q.failed++
+						q.err = err
+					}
+					q.mu.Unlock()
+				}
+			})
+		}
+	})
+}
+
+// PostText queues param for every destination without waiting for them.
+// It must not be called after Close.
+func (f *FanOut) PostText(ctx context.Context, param *PostTextParam) error {
+	f.start()
+
+	for i, d := range f.Destinations {
+		// Each destination gets its own copy in case it changes param
+		t := fanOutText{ctx: ctx, param: *param}
+		if d.Channel != "" {
+			t.param.Channel = d.Channel
+		}
+
+		q := f.queues[i]
+		select {
+		case q.texts <- t:
+		default:
+			f.Logger.Warn("dropped text since too many texts are waiting", slog.String("destination", d.Name), slog.Int("queue_size", f.QueueSize))
+			q.mu.Lock()
+			q.dropped++
+			q.mu.Unlock()
+		}
+	}
+
+	return nil
+}
+
+// Close waits until the queued texts are posted, and returns an error for
+// each destination which failed to post or dropped some texts.
+func (f *FanOut) Close() error {
+	f.start()
+	f.closeOnce.Do(func() {
+		for _, q := range f.queues {
+			close(q.texts)
+		}
+	})
+	f.wg.Wait()
+
+	var errs []error
+	for i, q := range f.queues {
+		name := f.Destinations[i].Name
+
+		q.mu.Lock()
+		if q.failed > 0 {
+			errs = append(errs, fmt.Errorf("%s: %d of %d texts failed: %w", name, q.failed, q.posts, q.err))
+		}
+		if q.dropped > 0 {
+			errs = append(errs, fmt.Errorf("%s: dropped %d texts since too many texts were waiting", name, q.dropped))
+		}
+		q.mu.Unlock()
+	}
+	return errors.Join(errs...)
+}
+
+// PostFile uploads the file with the first destination.
+func (f *FanOut) PostFile(ctx context.Context, param *PostFileParam, content []byte) error {
+	if len(f.Destinations) == 0 {
+		return nil
+	}
+	return f.Destinations[0].PostFile(ctx, param, content)
+}
+
+// PostMessage is not supported with several destinations.
+func (f *FanOut) PostMessage(context.Context, *PostTextParam) (*ChatRes, error) {
+	return nil, fmt.Errorf("posting a message to update is not supported with several destinations")
+}
+
+// UpdateMessage is not supported with several destinations.
+func (f *FanOut) UpdateMessage(context.Context, *UpdateMessageParam) error {
+	return fmt.Errorf("updating a message is not supported with several destinations")
+}
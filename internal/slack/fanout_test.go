@@ -0,0 +1,136 @@
+package slack_test
+
+import (
+	"encoding/json/v2"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	. "github.com/catatsuy/notify_slack/internal/slack"
+)
+
+func TestFanOut_PostText(t *testing.T) {
+	var posted atomic.Int32
+	var channels sync.Map
+
+	newDestination := func(name, channel string, status int) Destination {
+		muxAPI := http.NewServeMux()
+		testAPIServer := httptest.NewTestServer(t, muxAPI)
+		testHTTPClient := testAPIServer.Client()
+
+		muxAPI.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			var param PostTextParam
+			if err := json.UnmarshalRead(r.Body, &param); err != nil {
+				t.Fatal(err)
+			}
+			if param.Text != "testtesttest" {
+				t.Errorf("got %s, want %s", param.Text, "testtesttest")
+			}
+			channels.Store(name, param.Channel)
+			posted.Add(1)
+			w.WriteHeader(status)
+		})
+
+		c, err := NewClient(testAPIServer.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.HTTPClient = testHTTPClient
+		c.Retry.MaxAttempts = 1
+
+		return Destination{Name: name, Channel: channel, Slack: c}
+	}
+
+	f := NewFanOut([]Destination{
+		newDestination("team", "", http.StatusNotFound),
+		newDestination("ops", "#ops", http.StatusOK),
+		newDestination("audit", "", http.StatusBadRequest),
+	}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+
+	// PostText doesn't wait for the destinations, whose errors are
+	// returned by Close
+	if err := f.PostText(t.Context(), &PostTextParam{Channel: "#team", Text: "testtesttest"}); err != nil {
+		t.Fatal(err)
+	}
+	err := f.Close()
+	if err == nil {
+		t.Fatal("expected error, but nothing was returned")
+	}
+
+	// The text is posted to every destination even though some fail
+	if n := posted.Load(); n != 3 {
+		t.Errorf("got %d, want %d", n, 3)
+	}
+
+	for _, expected := range []string{"team: 1 of 1 texts failed: ", "status code: 404", "audit: ", "status code: 400"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q to contain %q", err.Error(), expected)
+		}
+	}
+	if strings.Contains(err.Error(), "ops") {
+		t.Errorf("expected %q not to contain %q", err.Error(), "ops")
+	}
+
+	// The channel of a destination replaces the channel of the text
+	for name, want := range map[string]string{"team": "#team", "ops": "#ops"} {
+		if got, _ := channels.Load(name); got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	}
+
+}
+
+func TestFanOut_queueSize(t *testing.T) {
+	entered := make(chan struct{}, 10)
+	release := make(chan struct{})
+	var posted atomic.Int32
+
+	muxAPI := http.NewServeMux()
+	testAPIServer := httptest.NewTestServer(t, muxAPI)
+	testHTTPClient := testAPIServer.Client()
+
+	muxAPI.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		entered <- struct{}{}
+		<-release
+		posted.Add(1)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	c, err := NewClient(testAPIServer.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.HTTPClient = testHTTPClient
+
+	f := NewFanOut([]Destination{{Name: "slow", Slack: c}}, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	f.QueueSize = 1
+
+	// The first text is being posted and the second one waits. The others
+	// are dropped.
+	for i := range 4 {
+		if err := f.PostText(t.Context(), &PostTextParam{Text: "testtesttest"}); err != nil {
+			t.Fatal(err)
+		}
+		if i == 0 {
+			<-entered
+		}
+	}
+	close(release)
+
+	err = f.Close()
+	if err == nil {
+		t.Fatal("expected error, but nothing was returned")
+	}
+	expected := "slow: dropped 2 texts"
+	if !strings.Contains(err.Error(), expected) {
+		t.Errorf("expected %q to contain %q", err.Error(), expected)
+	}
+	if n := posted.Load(); n != 2 {
+		t.Errorf("got %d, want %d", n, 2)
+	}
+}
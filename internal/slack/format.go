@@ -0,0 +1,96 @@
+package slack
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// TextFormat is how output is put into the text of a message, which Slack
+// parses as mrkdwn.
+//
+// https://api.slack.com/reference/surfaces/formatting#escaping
+type TextFormat int
+
+const (
+	// FormatRaw posts output as is, so that it is rendered as mrkdwn.
+	FormatRaw TextFormat = iota
+	// FormatEscape escapes &, < and >, so that no part of output is taken
+	// as a link, a mention or a date. Other formatting, such as *bold*, is
+	// still rendered.
+	FormatEscape
+	// FormatCode escapes output and puts it in a code block, so that it is
+	// shown literally in a monospace font.
+	FormatCode
+)
+
+var mrkdwnEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// EscapeText escapes the control characters of mrkdwn in text.
+func EscapeText(text string) string {
+	return mrkdwnEscaper.Replace(text)
+}
+
+// codeFenceBreaker puts a zero width space between two backticks, so that
+// backticks in text never close the code block around it.
+var codeFenceBreaker = strings.NewReplacer("``", "`\u200b`")
+
+// CodeBlock returns text escaped and put in a code block.
+func CodeBlock(text string) string {
+	text = codeFenceBreaker.Replace(EscapeText(text))
+	if !strings.HasSuffix(text, "\n") {
+		text += "\n"
+	}
+	return "```\n" + text + "```"
+}
+
+// Apply returns text formatted with f.
+func (f TextFormat) Apply(text string) string {
+	switch f {
+	case FormatEscape:
+		return EscapeText(text)
+	case FormatCode:
+		return CodeBlock(text)
+	}
+	return text
+}
+
+// Overhead returns the number of characters f adds around text, not counting
+// escapes.
+func (f TextFormat) Overhead() int {
+	if f == FormatCode {
+		return len("```\n\n```")
+	}
+	return 0
+}
+
+// Split splits text like Chunks and formats each chunk with f.
+func (f TextFormat) Split(text string, limit int) []string {
+	chunks := f.Chunks(text, limit)
+	for i, chunk := range chunks {
+		chunks[i] = f.Apply(chunk)
+	}
+	return chunks
+}
+
+// Chunks splits text like SplitText into chunks that each fit in limit
+// characters once formatted with f. The chunks are cut before formatting
+// so that each one is a whole code block. Escaping makes a chunk longer,
+// so chunks that grow past limit are split again.
+func (f TextFormat) Chunks(text string, limit int) []string {
+	if limit <= 0 {
+		return SplitText(text, limit)
+	}
+	return f.chunks(text, max(limit-f.Overhead(), 1), limit)
+}
+
+func (f TextFormat) chunks(text string, chunkLimit, limit int) []string {
+	var chunks []string
+	for _, chunk := range SplitText(text, chunkLimit) {
+		if chunkLimit > 1 && utf8.RuneCountInString(f.Apply(chunk)) > limit {
+			chunks = append(chunks, f.chunks(chunk, chunkLimit/2, limit)...)
+			continue
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
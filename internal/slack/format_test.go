@@ -0,0 +1,89 @@
+package slack_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	. "github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTextFormat_Apply(t *testing.T) {
+	text := "<!here> a & b > *c*\n"
+
+	tests := []struct {
+		format TextFormat
+		want   string
+	}{
+		{
+			format: FormatRaw,
+			want:   text,
+		},
+		{
+			format: FormatEscape,
+			want:   "&lt;!here&gt; a &amp; b &gt; *c*\n",
+		},
+		{
+			format: FormatCode,
+			want:   "```\n&lt;!here&gt; a &amp; b &gt; *c*\n```",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.format.Apply(text); got != tt.want {
+			t.Errorf("got %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestCodeBlock(t *testing.T) {
+	got := CodeBlock("run:\n```\nls\n````")
+
+	// Only the fences added around the text have three backticks in a row
+	if n := strings.Count(got, "```"); n != 2 {
+		t.Errorf("expected 2 fences in %q, got %d", got, n)
+	}
+	if !strings.HasPrefix(got, "```\n") || !strings.HasSuffix(got, "\n```") {
+		t.Errorf("expected %q to be a code block", got)
+	}
+	if removed := strings.ReplaceAll(got, "\u200b", ""); removed != "```\nrun:\n```\nls\n````\n```" {
+		t.Errorf("unexpected text in %q", got)
+	}
+}
+
+func TestTextFormat_Split(t *testing.T) {
+	// Each chunk is a whole code block within the limit
+	want := []string{"```\nabc\n```", "```\ndef\n```"}
+	if diff := cmp.Diff(want, FormatCode.Split("abc\ndef\n", 12)); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+
+	if got := FormatEscape.Split("", 12); got != nil {
+		t.Errorf("got %q, want nil", got)
+	}
+}
+
+func TestTextFormat_Split_escapes(t *testing.T) {
+	// Each < is escaped to 4 characters
+	text := strings.Repeat("<", 2992) + "\n" + strings.Repeat("a <b> & c\n", 20)
+
+	for _, f := range []TextFormat{FormatEscape, FormatCode} {
+		for _, limit := range []int{50, MaxSectionTextLength} {
+			chunks := f.Chunks(text, limit)
+			if got := strings.Join(chunks, ""); got != text {
+				t.Errorf("got %q, want %q", got, text)
+			}
+			for _, chunk := range f.Split(text, limit) {
+				if n := utf8.RuneCountInString(chunk); n > limit {
+					t.Errorf("got a chunk of %d characters, want at most %d: %q", n, limit, chunk)
+				}
+			}
+		}
+	}
+
+	// Text that fits is not split
+	if diff := cmp.Diff([]string{"```\na &lt;b&gt;\n```"}, FormatCode.Split("a <b>\n", 50)); diff != "" {
+		t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+	}
+}
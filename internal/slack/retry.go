@@ -0,0 +1,180 @@
+package slack
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultRetryMaxAttempts = 5
+	DefaultRetryMaxElapsed  = 2 * time.Minute
+)
+
+// RetryPolicy controls how requests to Slack are retried when Slack
+// rate limits the client (429), fails with a server error (5xx) or the
+// connection breaks.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one.
+	// A value of 1 or less disables retries.
+	MaxAttempts int
+	// MaxElapsed is the total time spent on a request, including waits,
+	// after which no further attempts are made.
+	MaxElapsed time.Duration
+	// BaseDelay is the wait before the first retry. It doubles on every
+	// following retry up to MaxDelay.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy returns the policy used by clients created with NewClient
+// and NewClientForPostFile.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: DefaultRetryMaxAttempts,
+		MaxElapsed:  DefaultRetryMaxElapsed,
+		BaseDelay:   time.Second,
+		MaxDelay:    30 * time.Second,
+	}
+}
+
+// backoff returns the wait before the given retry (1 for the first retry).
+// It applies jitter so that several processes hitting the same rate limit
+// don't retry in lockstep.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < retry && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
+
+// do sends the request built by newReq and returns the response together
+// with its body, retrying according to c.Retry. newReq is called for every
+// attempt because a request body can only be read once.
+//
+// When retries are exhausted, the last response is returned as is so that
+// callers report the status code and body they got from Slack.
+func (c *Client) do(ctx context.Context, newReq func() (*http.Request, error)) (*http.Response, []byte, error) {
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		req, err := newReq()
+		if err != nil {
+			return nil, nil, err
+		}
+		req = req.WithContext(ctx)
+
+		res, body, err := c.send(req)
+
+		wait, reason, retryable := c.Retry.check(ctx, res, err, attempt)
+		if !retryable {
+			return res, body, err
+		}
+		if wait == 0 {
+			wait = c.Retry.backoff(attempt)
+		}
+		if c.Retry.MaxElapsed > 0 && time.Since(start)+wait > c.Retry.MaxElapsed {
+			return res, body, err
+		}
+
+		c.Logger.Warn("retrying request",
+			slog.String("url", req.URL.String()),
+			slog.String("method", req.Method),
+			slog.Int("attempt", attempt),
+			slog.Duration("wait", wait),
+			slog.String("reason", reason),
+		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *Client) send(req *http.Request) (*http.Response, []byte, error) {
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read res.Body: %w", err)
+	}
+
+	return res, body, nil
+}
+
+// check reports whether the outcome of an attempt should be retried, and
+// the wait requested by Slack through Retry-After, if any.
+func (p RetryPolicy) check(ctx context.Context, res *http.Response, err error, attempt int) (wait time.Duration, reason string, retryable bool) {
+	if attempt >= p.MaxAttempts || ctx.Err() != nil {
+		return 0, "", false
+	}
+
+	if err != nil {
+		return 0, err.Error(), isTransient(err)
+	}
+
+	if res.StatusCode == http.StatusTooManyRequests || res.StatusCode >= 500 {
+		return retryAfter(res.Header.Get("Retry-After")), res.Status, true
+	}
+
+	return 0, "", false
+}
+
+// retryAfter parses the Retry-After header, which Slack sends in seconds.
+func retryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+
+	if sec, err := strconv.Atoi(v); err == nil && sec > 0 {
+		return time.Duration(sec) * time.Second
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+
+	return 0
+}
+
+// isTransient reports whether err is a network error that is likely to go
+// away when the request is sent again.
+func isTransient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
@@ -0,0 +1,161 @@
+package slack_test
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/synctest"
+	"time"
+
+	. "github.com/catatsuy/notify_slack/internal/slack"
+)
+
+func TestPostText_RetryAfter(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		muxAPI := http.NewServeMux()
+		testAPIServer := httptest.NewTestServer(t, muxAPI)
+		testHTTPClient := testAPIServer.Client()
+
+		var requestTimes []time.Time
+		muxAPI.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.Contains(string(b), "testtesttest") {
+				t.Errorf("unexpected body on retry: %s", b)
+			}
+
+			requestTimes = append(requestTimes, time.Now())
+			if len(requestTimes) == 1 {
+				w.Header().Set("Retry-After", "3")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			w.Write([]byte("ok"))
+		})
+
+		logs := new(bytes.Buffer)
+		c, err := NewClient(testAPIServer.URL, slog.New(slog.NewTextHandler(logs, nil)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.HTTPClient = testHTTPClient
+
+		err = c.PostText(t.Context(), &PostTextParam{Text: "testtesttest"})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(requestTimes) != 2 {
+			t.Fatalf("got %d requests, want 2", len(requestTimes))
+		}
+		if d := requestTimes[1].Sub(requestTimes[0]); d != 3*time.Second {
+			t.Errorf("retried after %s, want %s", d, 3*time.Second)
+		}
+		if !strings.Contains(logs.String(), "retrying request") {
+			t.Errorf("expected retry to be logged, got %q", logs.String())
+		}
+	})
+}
+
+func TestPostText_RetryServerError(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		muxAPI := http.NewServeMux()
+		testAPIServer := httptest.NewTestServer(t, muxAPI)
+		testHTTPClient := testAPIServer.Client()
+
+		count := 0
+		muxAPI.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			count++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+
+		c, err := NewClient(testAPIServer.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.HTTPClient = testHTTPClient
+		c.Retry.MaxAttempts = 3
+
+		err = c.PostText(t.Context(), &PostTextParam{Text: "testtesttest"})
+		if err == nil {
+			t.Fatal("expected error, but nothing was returned")
+		}
+
+		expected := "status code: 503"
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q to contain %q", err.Error(), expected)
+		}
+		if count != 3 {
+			t.Errorf("got %d requests, want 3", count)
+		}
+	})
+}
+
+func TestPostText_RetryMaxElapsed(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		muxAPI := http.NewServeMux()
+		testAPIServer := httptest.NewTestServer(t, muxAPI)
+		testHTTPClient := testAPIServer.Client()
+
+		count := 0
+		muxAPI.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			count++
+			w.Header().Set("Retry-After", "60")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+
+		c, err := NewClient(testAPIServer.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.HTTPClient = testHTTPClient
+		c.Retry.MaxElapsed = 90 * time.Second
+
+		err = c.PostText(t.Context(), &PostTextParam{Text: "testtesttest"})
+		if err == nil {
+			t.Fatal("expected error, but nothing was returned")
+		}
+
+		expected := "status code: 429"
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected %q to contain %q", err.Error(), expected)
+		}
+		if count != 2 {
+			t.Errorf("got %d requests, want 2", count)
+		}
+	})
+}
+
+func TestPostText_NoRetryOnClientError(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		muxAPI := http.NewServeMux()
+		testAPIServer := httptest.NewTestServer(t, muxAPI)
+		testHTTPClient := testAPIServer.Client()
+
+		count := 0
+		muxAPI.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			count++
+			w.WriteHeader(http.StatusBadRequest)
+		})
+
+		c, err := NewClient(testAPIServer.URL, slog.New(slog.NewTextHandler(io.Discard, nil)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		c.HTTPClient = testHTTPClient
+
+		err = c.PostText(t.Context(), &PostTextParam{Text: "testtesttest"})
+		if err == nil {
+			t.Fatal("expected error, but nothing was returned")
+		}
+		if count != 1 {
+			t.Errorf("got %d requests, want 1", count)
+		}
+	})
+}
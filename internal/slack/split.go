@@ -0,0 +1,130 @@
+package slack
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultTextLimit is the number of characters put into a single message
+// unless configured otherwise. Slack truncates the text field of long
+// messages, and recommends keeping it under 4,000 characters.
+const DefaultTextLimit = 4000
+
+// SplitText splits text into chunks of at most limit characters.
+// Chunks are cut on line boundaries whenever possible. A single line longer
+// than limit is cut between grapheme clusters so that multi-byte characters,
+// combining marks and emoji sequences are never broken apart.
+func SplitText(text string, limit int) []string {
+	if text == "" {
+		return nil
+	}
+	if limit <= 0 || utf8.RuneCountInString(text) <= limit {
+		return []string{text}
+	}
+
+	var chunks []string
+	var current strings.Builder
+	currentLen := 0
+
+	for line := range strings.Lines(text) {
+		lineLen := utf8.RuneCountInString(line)
+
+		if currentLen+lineLen <= limit {
+			current.WriteString(line)
+			currentLen += lineLen
+			continue
+		}
+
+		if currentLen > 0 {
+			chunks = append(chunks, current.String())
+			current.Reset()
+			currentLen = 0
+		}
+
+		for lineLen > limit {
+			head, tail := cutLine(line, limit)
+			chunks = append(chunks, head)
+			line = tail
+			lineLen = utf8.RuneCountInString(line)
+		}
+
+		current.WriteString(line)
+		currentLen = lineLen
+	}
+
+	if currentLen > 0 {
+		chunks = append(chunks, current.String())
+	}
+
+	return chunks
+}
+
+// cutLine cuts s into a head of at most limit characters and the rest.
+// The cut is moved back to the nearest grapheme cluster boundary.
+func cutLine(s string, limit int) (head, tail string) {
+	runes := []rune(s)
+
+	cut := limit
+	for cut > 0 && !isGraphemeBoundary(runes, cut) {
+		cut--
+	}
+	if cut == 0 {
+		// A single cluster longer than limit. There is nothing better to do
+		// than cutting it.
+		cut = limit
+	}
+
+	return string(runes[:cut]), string(runes[cut:])
+}
+
+// isGraphemeBoundary reports whether a message can be cut before runes[i].
+// It covers the cases that matter for log output rather than the full
+// Unicode segmentation rules.
+func isGraphemeBoundary(runes []rune, i int) bool {
+	prev, next := runes[i-1], runes[i]
+
+	if prev == '\r' && next == '\n' {
+		return false
+	}
+	if prev == zeroWidthJoiner || isExtender(next) {
+		return false
+	}
+	if isRegionalIndicator(prev) && isRegionalIndicator(next) {
+		// Flags are pairs of regional indicators. Only cut after an even
+		// number of them.
+		n := 0
+		for j := i - 1; j >= 0 && isRegionalIndicator(runes[j]); j-- {
+			n++
+		}
+		return n%2 == 0
+	}
+
+	return true
+}
+
+const zeroWidthJoiner = '\u200d'
+
+func isExtender(r rune) bool {
+	switch {
+	case r == zeroWidthJoiner:
+		return true
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		// combining marks, including Japanese dakuten (U+3099, U+309A)
+		return true
+	case 0xFE00 <= r && r <= 0xFE0F, 0xE0100 <= r && r <= 0xE01EF:
+		// variation selectors
+		return true
+	case 0x1F3FB <= r && r <= 0x1F3FF:
+		// emoji skin tone modifiers
+		return true
+	case 0xE0020 <= r && r <= 0xE007F:
+		// emoji tag sequences
+		return true
+	}
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return 0x1F1E6 <= r && r <= 0x1F1FF
+}
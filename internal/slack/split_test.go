@@ -0,0 +1,112 @@
+package slack_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	. "github.com/catatsuy/notify_slack/internal/slack"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSplitText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		limit int
+		want  []string
+	}{
+		{
+			name:  "empty",
+			text:  "",
+			limit: 10,
+			want:  nil,
+		},
+		{
+			name:  "fits",
+			text:  "abc\ndef\n",
+			limit: 10,
+			want:  []string{"abc\ndef\n"},
+		},
+		{
+			name:  "no limit",
+			text:  "abc\ndef\n",
+			limit: 0,
+			want:  []string{"abc\ndef\n"},
+		},
+		{
+			name:  "line boundaries",
+			text:  "abc\ndef\nghi\n",
+			limit: 8,
+			want:  []string{"abc\ndef\n", "ghi\n"},
+		},
+		{
+			name:  "long line",
+			text:  "ab\nabcdefghij\ncd\n",
+			limit: 4,
+			want:  []string{"ab\n", "abcd", "efgh", "ij\n", "cd\n"},
+		},
+		{
+			name:  "japanese",
+			text:  "あいうえおかきくけこ",
+			limit: 4,
+			want:  []string{"あいうえ", "おかきく", "けこ"},
+		},
+		{
+			name:  "combining mark",
+			text:  "か\u304b\u3099か",
+			limit: 2,
+			want:  []string{"か", "\u304b\u3099", "か"},
+		},
+		{
+			name:  "zwj emoji sequence",
+			text:  "a\U0001F468\u200d\U0001F469\u200d\U0001F467b",
+			limit: 5,
+			want:  []string{"a", "\U0001F468\u200d\U0001F469\u200d\U0001F467", "b"},
+		},
+		{
+			name:  "skin tone",
+			text:  "a👍\U0001F3FD",
+			limit: 2,
+			want:  []string{"a", "👍\U0001F3FD"},
+		},
+		{
+			name:  "flags",
+			text:  "🇯🇵🇺🇸",
+			limit: 3,
+			want:  []string{"🇯🇵", "🇺🇸"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SplitText(tt.text, tt.limit)
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSplitText_keepsContent(t *testing.T) {
+	var b strings.Builder
+	for i := range 1000 {
+		b.WriteString(strings.Repeat("ログ", i%50))
+		b.WriteString("\n")
+	}
+	text := b.String()
+
+	chunks := SplitText(text, 100)
+	for _, c := range chunks {
+		if n := utf8.RuneCountInString(c); n > 100 {
+			t.Fatalf("chunk has %d characters; want at most 100", n)
+		}
+		if !utf8.ValidString(c) {
+			t.Fatalf("chunk is not valid UTF-8: %q", c)
+		}
+	}
+
+	if got := strings.Join(chunks, ""); got != text {
+		t.Error("joined chunks differ from the original text")
+	}
+}
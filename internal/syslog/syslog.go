@@ -0,0 +1,296 @@
+// Package syslog parses syslog messages in the formats of RFC 3164 and
+// RFC 5424.
+package syslog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Severity is the severity of a message. Lower values are more severe.
+type Severity int
+
+const (
+	Emergency Severity = iota
+	Alert
+	Critical
+	Error
+	Warning
+	Notice
+	Info
+	Debug
+)
+
+var severityNames = []string{"emerg", "alert", "crit", "err", "warning", "notice", "info", "debug"}
+
+// severityAliases are the other names accepted by ParseSeverity.
+var severityAliases = map[string]Severity{
+	"emergency": Emergency,
+	"panic":     Emergency,
+	"critical":  Critical,
+	"error":     Error,
+	"warn":      Warning,
+}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return strconv.Itoa(int(s))
+	}
+	return severityNames[s]
+}
+
+// ParseSeverity parses a severity given by its name, such as warning or
+// err, or by its number.
+func ParseSeverity(s string) (Severity, error) {
+	name := strings.ToLower(s)
+	for i, n := range severityNames {
+		if name == n {
+			return Severity(i), nil
+		}
+	}
+	if sev, ok := severityAliases[name]; ok {
+		return sev, nil
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 0 && n < len(severityNames) {
+		return Severity(n), nil
+	}
+	return 0, fmt.Errorf("incorrect value to syslog severity option: %s: must be one of %s", s, strings.Join(severityNames, ", "))
+}
+
+var facilityNames = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+// Facility is the facility of a message, such as kern or local0.
+type Facility int
+
+func (f Facility) String() string {
+	if f < 0 || int(f) >= len(facilityNames) {
+		return strconv.Itoa(int(f))
+	}
+	return facilityNames[f]
+}
+
+// Message is a parsed syslog message. The fields missing from the message
+// are left empty.
+type Message struct {
+	Facility  Facility
+	Severity  Severity
+	Timestamp time.Time
+	Hostname  string
+	AppName   string
+	ProcID    string
+	MsgID     string
+	Content   string
+}
+
+// ErrInvalid is returned for data that is not a syslog message.
+var ErrInvalid = errors.New("invalid syslog message")
+
+// Parse parses a message in the format of RFC 5424, or of RFC 3164 as sent
+// by most devices and by rsyslog. Messages of RFC 3164 without a timestamp
+// or a tag are accepted, with the rest of the message as the content.
+func Parse(data []byte) (*Message, error) {
+	data = bytes.TrimRight(data, "\r\n\x00")
+
+	pri, rest, err := parsePRI(data)
+	if err != nil {
+		return nil, err
+	}
+	m := &Message{
+		Facility: Facility(pri / 8),
+		Severity: Severity(pri % 8),
+	}
+
+	if after, ok := strings.CutPrefix(string(rest), "1 "); ok {
+		if err := parse5424(m, after); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
+	parse3164(m, string(rest))
+	return m, nil
+}
+
+// parsePRI parses the priority in angle brackets at the start of data.
+func parsePRI(data []byte) (int, []byte, error) {
+	if len(data) < 3 || data[0] != '<' {
+		return 0, nil, fmt.Errorf("%w: missing priority", ErrInvalid)
+	}
+	end := bytes.IndexByte(data[:min(len(data), 5)], '>')
+	if end < 2 {
+		return 0, nil, fmt.Errorf("%w: missing priority", ErrInvalid)
+	}
+	pri, err := strconv.Atoi(string(data[1:end]))
+	if err != nil || pri < 0 || pri > 191 {
+		return 0, nil, fmt.Errorf("%w: incorrect priority: %s", ErrInvalid, data[1:end])
+	}
+	return pri, data[end+1:], nil
+}
+
+// parse5424 parses the part of an RFC 5424 message after the version.
+func parse5424(m *Message, s string) error {
+	var fields [5]string
+	for i := range fields {
+		field, rest, ok := strings.Cut(s, " ")
+		if !ok && i < len(fields)-1 {
+			return fmt.Errorf("%w: missing header fields", ErrInvalid)
+		}
+		fields[i] = nilValue(field)
+		s = rest
+	}
+
+	if fields[0] != "" {
+		ts, err := time.Parse(time.RFC3339Nano, fields[0])
+		if err != nil {
+			return fmt.Errorf("%w: incorrect timestamp: %s", ErrInvalid, fields[0])
+		}
+		m.Timestamp = ts
+	}
+	m.Hostname = fields[1]
+	m.AppName = fields[2]
+	m.ProcID = fields[3]
+	m.MsgID = fields[4]
+
+	msg, err := skipStructuredData(s)
+	if err != nil {
+		return err
+	}
+	// The message may start with a BOM to tell it is UTF-8
+	m.Content = strings.TrimPrefix(msg, "\uFEFF")
+	return nil
+}
+
+func nilValue(field string) string {
+	if field == "-" {
+		return ""
+	}
+	return field
+}
+
+// skipStructuredData returns the message following the structured data at
+// the start of s.
+func skipStructuredData(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+	if s == "-" || strings.HasPrefix(s, "- ") {
+		return strings.TrimPrefix(s[1:], " "), nil
+	}
+	if s[0] != '[' {
+		return "", fmt.Errorf("%w: incorrect structured data", ErrInvalid)
+	}
+
+	inElement, quoted, escaped := false, false, false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case quoted:
+			switch c {
+			case '\\':
+				escaped = true
+			case '"':
+				quoted = false
+			}
+		case inElement:
+			switch c {
+			case '"':
+				quoted = true
+			case ']':
+				inElement = false
+			}
+		case c == '[':
+			inElement = true
+		case c == ' ':
+			return s[i+1:], nil
+		default:
+			return "", fmt.Errorf("%w: incorrect structured data", ErrInvalid)
+		}
+	}
+	if inElement {
+		return "", fmt.Errorf("%w: unterminated structured data", ErrInvalid)
+	}
+	return "", nil
+}
+
+// parse3164 parses the part of an RFC 3164 message after the priority.
+// What can't be parsed is kept in the content.
+func parse3164(m *Message, s string) {
+	ts, rest, ok := parse3164Timestamp(s)
+	if !ok {
+		m.Content = s
+		return
+	}
+	m.Timestamp = ts
+
+	host, rest, ok := strings.Cut(rest, " ")
+	if !ok {
+		m.Content = rest
+		return
+	}
+	m.Hostname = host
+
+	m.AppName, m.ProcID, m.Content = parseTag(rest)
+}
+
+// parse3164Timestamp parses the timestamp at the start of s, either like
+// "Jan  2 15:04:05" or in the format of RFC 3339 as sent by rsyslog.
+func parse3164Timestamp(s string) (time.Time, string, bool) {
+	if len(s) > len(time.Stamp) && s[len(time.Stamp)] == ' ' {
+		ts, err := time.ParseInLocation(time.Stamp, s[:len(time.Stamp)], time.Local)
+		if err == nil {
+			// The year is not sent. Assume the closest one.
+			now := time.Now()
+			ts = ts.AddDate(now.Year(), 0, 0)
+			if ts.After(now.AddDate(0, 1, 0)) {
+				ts = ts.AddDate(-1, 0, 0)
+			}
+			return ts, s[len(time.Stamp)+1:], true
+		}
+	}
+
+	field, rest, ok := strings.Cut(s, " ")
+	if !ok {
+		return time.Time{}, "", false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, field)
+	if err != nil {
+		return time.Time{}, "", false
+	}
+	return ts, rest, true
+}
+
+// parseTag splits "app[pid]: content" into its parts. s is returned as the
+// content if it doesn't start with a tag.
+func parseTag(s string) (app, pid, content string) {
+	end := strings.IndexAny(s, ":[ ")
+	if end <= 0 {
+		return "", "", s
+	}
+	app = s[:end]
+	rest := s[end:]
+
+	if strings.HasPrefix(rest, "[") {
+		p, after, ok := strings.Cut(rest[1:], "]")
+		if !ok {
+			return "", "", s
+		}
+		pid = p
+		rest = after
+	}
+
+	after, ok := strings.CutPrefix(rest, ":")
+	if !ok {
+		return "", "", s
+	}
+	return app, pid, strings.TrimPrefix(after, " ")
+}
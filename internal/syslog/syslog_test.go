@@ -0,0 +1,142 @@
+package syslog_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/catatsuy/notify_slack/internal/syslog"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected Message
+	}{
+		{
+			name:  "RFC 5424",
+			input: `<165>1 2003-10-11T22:14:15.003Z mymachine.example.com evntslog - ID47 [exampleSDID@32473 iut="3" eventSource="Application] \"x\""] An application event log entry...`,
+			expected: Message{
+				Facility:  Facility(20),
+				Severity:  Notice,
+				Timestamp: time.Date(2003, 10, 11, 22, 14, 15, 3000000, time.UTC),
+				Hostname:  "mymachine.example.com",
+				AppName:   "evntslog",
+				MsgID:     "ID47",
+				Content:   "An application event log entry...",
+			},
+		},
+		{
+			name:  "RFC 5424 without structured data",
+			input: "<11>1 - web1 nginx 42 - - \uFEFFupstream timed out\n",
+			expected: Message{
+				Facility: Facility(1),
+				Severity: Error,
+				Hostname: "web1",
+				AppName:  "nginx",
+				ProcID:   "42",
+				Content:  "upstream timed out",
+			},
+		},
+		{
+			name:  "RFC 3164",
+			input: "<34>Oct 11 22:14:15 mymachine su[230]: 'su root' failed for lonvick on /dev/pts/8",
+			expected: Message{
+				Facility: Facility(4),
+				Severity: Critical,
+				Hostname: "mymachine",
+				AppName:  "su",
+				ProcID:   "230",
+				Content:  "'su root' failed for lonvick on /dev/pts/8",
+			},
+		},
+		{
+			name:  "RFC 3164 with an RFC 3339 timestamp",
+			input: "<12>2024-05-01T10:00:00+09:00 router kernel: link down",
+			expected: Message{
+				Facility:  Facility(1),
+				Severity:  Warning,
+				Timestamp: time.Date(2024, 5, 1, 10, 0, 0, 0, time.FixedZone("", 9*60*60)),
+				Hostname:  "router",
+				AppName:   "kernel",
+				Content:   "link down",
+			},
+		},
+		{
+			name:  "RFC 3164 without a tag",
+			input: "<13>Feb  5 17:32:18 10.0.0.99 Use the BFG!",
+			expected: Message{
+				Facility: Facility(1),
+				Severity: Notice,
+				Hostname: "10.0.0.99",
+				Content:  "Use the BFG!",
+			},
+		},
+		{
+			name:  "only a priority",
+			input: "<14>hello world",
+			expected: Message{
+				Facility: Facility(1),
+				Severity: Info,
+				Content:  "hello world",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := Parse([]byte(tt.input))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The year of RFC 3164 timestamps depends on the current date
+			ignoreTimestamp := tt.expected.Timestamp.IsZero() && !m.Timestamp.IsZero()
+			if ignoreTimestamp {
+				m.Timestamp = time.Time{}
+			}
+			if diff := cmp.Diff(tt.expected, *m, cmpopts.EquateComparable(time.Time{})); diff != "" {
+				t.Errorf("unexpected diff: (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestParse_invalid(t *testing.T) {
+	for _, input := range []string{"", "hello", "<>", "<192>hello", "<13>1 2003-10-11T22:14:15Z host app - - [unterminated"} {
+		if _, err := Parse([]byte(input)); !errors.Is(err, ErrInvalid) {
+			t.Errorf("expected ErrInvalid for %q, got %v", input, err)
+		}
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected Severity
+	}{
+		{"warning", Warning},
+		{"WARN", Warning},
+		{"err", Error},
+		{"error", Error},
+		{"crit", Critical},
+		{"3", Error},
+		{"debug", Debug},
+	}
+	for _, tt := range tests {
+		got, err := ParseSeverity(tt.input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.expected {
+			t.Errorf("ParseSeverity(%q) = %s, want %s", tt.input, got, tt.expected)
+		}
+	}
+
+	if _, err := ParseSeverity("loud"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -5,9 +5,12 @@ import (
 	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"io"
+	"slices"
 	"sync"
 	"time"
+	"unicode/utf8"
 )
 
 // Exec reads input line by line and buffers it, flushing at specified intervals.
@@ -18,18 +21,129 @@ type Exec struct {
 	pr     *io.PipeReader // Optional: for pipe-specific closing
 
 	buffer *bytes.Buffer
-	mu     sync.Mutex // Protects buffer access
+	lines  int        // Number of lines in buffer
+	mu     sync.Mutex // Protects buffer, lines and the dedupe state
 
-	done chan struct{} // Signals when reading is complete
+	dedupe  DedupeMode
+	lastKey string // dedupe key of the last line in buffer
+	repeats int    // times the last line was repeated and not buffered
+
+	maxLineBytes int
+	stages       []Stage
+
+	policy      FlushPolicy
+	errorPolicy ErrorPolicy
+	appended    chan struct{} // Signals that a line has been buffered
+
+	silence         time.Duration
+	silenceCallback func(ctx context.Context, ev SilenceEvent)
+	received        chan struct{} // Signals that a line has been read
+	lastLine        string        // last line passed by the stages
+
+	done    chan struct{} // Signals when reading is complete
+	readErr error         // Unexpected read error, set before done is closed
+}
+
+// FlushPolicy decides when buffered lines are flushed in addition to the
+// interval passed to Start. The conditions can be combined; the buffer is
+// flushed as soon as any of them is met. The zero value flushes on the
+// interval only.
+type FlushPolicy struct {
+	// MaxBytes flushes once the buffer holds at least this many bytes.
+	MaxBytes int
+	// MaxLines flushes once the buffer holds at least this many lines.
+	MaxLines int
+	// Quiet flushes once no line has been read for this duration, so that
+	// a burst of output is posted together.
+	Quiet time.Duration
+	// MaxDelay flushes a line at the latest this long after it was read,
+	// even if the input never becomes quiet.
+	MaxDelay time.Duration
+	// FirstLine flushes the very first line immediately for low latency
+	// feedback.
+	FirstLine bool
+}
+
+// A Stage processes each line read from the input before it is buffered.
+// It passes the lines to buffer to emit, which it may call any number of
+// times, including none to drop the line. line is only valid during the
+// call.
+type Stage func(line []byte, emit func(line []byte))
+
+// AddStage adds a stage after the stages added before. It must be called
+// before Start.
+func (ex *Exec) AddStage(stage Stage) {
+	ex.stages = append(ex.stages, stage)
+}
+
+// DedupeMode decides which consecutive lines are folded into one line with
+// a repeat counter. Lines are only folded within a flush.
+type DedupeMode int
+
+const (
+	// DedupeOff keeps all lines.
+	DedupeOff DedupeMode = iota
+	// DedupeExact folds identical lines.
+	DedupeExact
+	// DedupeNormalized folds lines that differ only in numbers, such as
+	// timestamps, counters or IDs. The first of the lines is kept.
+	DedupeNormalized
+)
+
+// SetDedupe sets the mode for folding repeated lines. It must be called
+// before Start.
+func (ex *Exec) SetDedupe(mode DedupeMode) {
+	ex.dedupe = mode
+}
+
+// SetMaxLineBytes sets the length at which lines are truncated. Zero or a
+// negative value keeps lines of any length. It must be called before Start.
+func (ex *Exec) SetMaxLineBytes(n int) {
+	ex.maxLineBytes = n
+}
+
+// SilenceEvent tells that no line has been read for a while, or that lines
+// are read again after that.
+type SilenceEvent struct {
+	// Resumed is false when the input becomes silent, and true when a line
+	// is read again afterwards.
+	Resumed bool
+	// LastLine is the last line passed by the stages: the last one before
+	// the silence, or the one that ended it when Resumed is set. It is empty
+	// if no line has been passed yet.
+	LastLine string
+	// Elapsed is how long no line has been read.
+	Elapsed time.Duration
+}
+
+// SetSilenceAlert makes Start call callback when no line has been read for
+// d, counting from the start, and once more when a line is read after that.
+// Lines dropped by the stages count as read. It must be called before
+// Start.
+func (ex *Exec) SetSilenceAlert(d time.Duration, callback func(ctx context.Context, ev SilenceEvent)) {
+	ex.silence = d
+	ex.silenceCallback = callback
+}
+
+// SetFlushPolicy sets the policy. It must be called before Start.
+func (ex *Exec) SetFlushPolicy(policy FlushPolicy) {
+	ex.policy = policy
 }
 
+// DefaultMaxLineBytes is the length at which lines are truncated unless
+// configured otherwise.
+const DefaultMaxLineBytes = 64 * 1024
+
 // NewExec creates a new Exec that reads from the given input.
 func NewExec(input io.Reader) *Exec {
 	ex := &Exec{
-		reader: bufio.NewReader(input),
-		buffer: new(bytes.Buffer),
-		done:   make(chan struct{}),
-		mu:     sync.Mutex{},
+		reader:       bufio.NewReader(input),
+		maxLineBytes: DefaultMaxLineBytes,
+		buffer:       new(bytes.Buffer),
+		appended:     make(chan struct{}, 1),
+		received:     make(chan struct{}, 1),
+		done:         make(chan struct{}),
+		mu:           sync.Mutex{},
 	}
 
 	// Store references to closers if the input supports closing
@@ -46,38 +160,156 @@ func NewExec(input io.Reader) *Exec {
 
 // Start begins reading input and processing it.
 // - Reads input line by line in a background goroutine
-// - Flushes buffered content on each interval tick
+// - Flushes buffered content on each interval tick and when the flush policy says so
 // - Calls doneCallback with remaining content when input closes or context is cancelled
+//
+// The returned error reports failed callbacks and unexpected read errors.
+// Depending on the error policy, a failed flushCallback either stops
+// processing right away or is counted and processing continues.
 func (ex *Exec) Start(
 	ctx context.Context,
 	interval <-chan time.Time,
 	flushCallback func(ctx context.Context, output string) error,
 	doneCallback func(ctx context.Context, output string) error,
-) {
+) (Result, error) {
 	// Start background reader
 	go ex.readInput()
 
 	// Process events
-	ex.processEvents(ctx, interval, flushCallback, doneCallback)
+	return ex.processEvents(ctx, interval, flushCallback, doneCallback)
+}
+
+// ErrorPolicy decides what happens when flushCallback returns an error.
+type ErrorPolicy int
+
+const (
+	// ContinueOnError keeps reading and flushing after a failed flush.
+	ContinueOnError ErrorPolicy = iota
+	// AbortOnError stops reading, without calling doneCallback, as soon as
+	// a flush fails.
+	AbortOnError
+)
+
+// SetErrorPolicy sets the error policy. It must be called before Start.
+func (ex *Exec) SetErrorPolicy(policy ErrorPolicy) {
+	ex.errorPolicy = policy
+}
+
+// Result holds statistics about the callbacks called by Start.
+type Result struct {
+	// Flushes is the number of callbacks called with some output,
+	// including doneCallback.
+	Flushes int
+	// FailedFlushes is the number of those callbacks that returned an error.
+	FailedFlushes int
+	// BytesPosted is the size of the output passed to the callbacks that
+	// succeeded.
+	BytesPosted int64
+}
+
+// record updates the statistics with the outcome of a callback called with
+// output, and returns the error to report for it.
+func (r *Result) record(output string, err error) error {
+	if output == "" && err == nil {
+		return nil
+	}
+
+	r.Flushes++
+	if err != nil {
+		r.FailedFlushes++
+		return err
+	}
+	r.BytesPosted += int64(len(output))
+	return nil
 }
 
 // readInput reads lines from input until EOF or error
 func (ex *Exec) readInput() {
 	defer close(ex.done)
 
+	// Chain the stages in front of the buffer
+	emit := ex.appendLine
+	for _, stage := range slices.Backward(ex.stages) {
+		next := emit
+		emit = func(line []byte) {
+			stage(line, next)
+		}
+	}
+
 	for {
-		line, _, err := ex.reader.ReadLine()
+		line, err := ex.readLine()
+		if line != nil {
+			emit(line)
+			ex.signalReceived()
+		}
 		if err != nil {
 			if errors.Is(err, io.EOF) ||
 				errors.Is(err, io.ErrClosedPipe) ||
 				errors.Is(err, context.Canceled) {
 				return
 			}
-			panic(err) // Unexpected error
+			ex.readErr = err
+			return
+		}
+	}
+}
+
+// signalReceived wakes up processEvents to reset the silence timer without
+// blocking the reader
+func (ex *Exec) signalReceived() {
+	if ex.silence <= 0 {
+		return
+	}
+	select {
+	case ex.received <- struct{}{}:
+	default:
+	}
+}
+
+// readLine reads a whole line, reassembling the fragments returned by
+// ReadLine for lines longer than the bufio buffer. Lines longer than
+// maxLineBytes are truncated and end with a marker telling how many bytes
+// were dropped. A nil line is returned when nothing was read.
+func (ex *Exec) readLine() ([]byte, error) {
+	var line []byte
+	truncated := 0
+
+	for {
+		fragment, isPrefix, err := ex.reader.ReadLine()
+		if err != nil {
+			return ex.truncationMarker(line, truncated), err
+		}
+
+		keep := len(fragment)
+		if truncated > 0 {
+			// Nothing after the cut is kept, even if the cut left room
+			keep = 0
+			truncated += len(fragment)
+		} else if ex.maxLineBytes > 0 && len(line)+keep > ex.maxLineBytes {
+			keep = max(ex.maxLineBytes-len(line), 0)
+			// Don't cut a multi-byte character in half
+			for keep > 0 && !utf8.RuneStart(fragment[keep]) {
+				keep--
+			}
+			truncated += len(fragment) - keep
+		}
+		// ReadLine's result is only valid until the next read
+		line = append(line, fragment[:keep]...)
+		if line == nil {
+			line = []byte{}
 		}
 
-		ex.appendLine(line)
+		if !isPrefix {
+			return ex.truncationMarker(line, truncated), nil
+		}
+	}
+}
+
+func (ex *Exec) truncationMarker(line []byte, truncated int) []byte {
+	if truncated == 0 {
+		return line
 	}
+	return fmt.Appendf(line, "…[truncated %d bytes]", truncated)
 }
 
 // processEvents handles interval ticks and completion events
@@ -86,34 +318,225 @@ func (ex *Exec) processEvents(
 	interval <-chan time.Time,
 	flushCallback func(ctx context.Context, output string) error,
 	doneCallback func(ctx context.Context, output string) error,
-) {
+) (Result, error) {
+	var quiet, deadline, silence policyTimer
+	defer quiet.stop()
+	defer deadline.stop()
+	defer silence.stop()
+
+	// lastRead is when a line was last read, and silent tells whether the
+	// silence has been reported since
+	lastRead := time.Now()
+	silent := false
+	if ex.silence > 0 {
+		silence.reset(ex.silence)
+	}
+	resume := func() {
+		if !silent {
+			return
+		}
+		silent = false
+		ex.silenceCallback(ctx, SilenceEvent{
+			Resumed:  true,
+			LastLine: ex.getLastLine(),
+			Elapsed:  time.Since(lastRead),
+		})
+	}
+
+	var result Result
+	var flushErr error // the first error returned by a callback
+
+	flushed := false
+	flush := func() (abort bool) {
+		quiet.stop()
+		deadline.stop()
+
+		output := ex.getAndResetBuffer()
+		if output != "" {
+			flushed = true
+		}
+
+		err := result.record(output, flushCallback(ctx, output))
+		if err == nil {
+			return false
+		}
+		if flushErr == nil {
+			flushErr = err
+		}
+		return ex.errorPolicy == AbortOnError
+	}
+
+	// finish reports the outcome once processing is over
+	finish := func(doneErr, readErr error) (Result, error) {
+		var errs []error
+		if readErr != nil {
+			errs = append(errs, fmt.Errorf("failed to read input: %w", readErr))
+		}
+		if flushErr == nil {
+			flushErr = doneErr
+		}
+		if flushErr != nil {
+			errs = append(errs, fmt.Errorf("%d of %d flushes failed: %w", result.FailedFlushes, result.Flushes, flushErr))
+		}
+		return result, errors.Join(errs...)
+	}
+
 	for {
+		var abort bool
+
 		select {
 		case <-interval:
 			// Periodic flush of buffered content
-			flushCallback(ctx, ex.getAndResetBuffer())
+			abort = flush()
+
+		case <-ex.appended:
+			if ex.shouldFlush(!flushed) {
+				abort = flush()
+				break
+			}
+			if ex.policy.Quiet > 0 {
+				quiet.reset(ex.policy.Quiet)
+			}
+			if ex.policy.MaxDelay > 0 && !deadline.armed() {
+				deadline.reset(ex.policy.MaxDelay)
+			}
+
+		case <-ex.received:
+			resume()
+			lastRead = time.Now()
+			silence.reset(ex.silence)
+
+		case <-silence.c:
+			silence.c = nil
+			silent = true
+			// Post what was read before the silence first
+			abort = flush()
+			ex.silenceCallback(ctx, SilenceEvent{
+				LastLine: ex.getLastLine(),
+				Elapsed:  time.Since(lastRead),
+			})
+
+		case <-quiet.c:
+			quiet.c = nil
+			abort = flush()
+
+		case <-deadline.c:
+			deadline.c = nil
+			abort = flush()
 
 		case <-ctx.Done():
 			// Context cancelled - stop reading and flush remaining content
 			ex.closeInput(ctx.Err())
-			doneCallback(ctx, ex.getAndResetBuffer())
-			return
+			output := ex.getAndResetBuffer()
+			err := result.record(output, doneCallback(ctx, output))
+			return finish(err, nil)
 
 		case <-ex.done:
+			// Report the lines that ended a silence right before the end
+			select {
+			case <-ex.received:
+				resume()
+			default:
+			}
+
 			// Input closed - flush remaining content
-			doneCallback(ctx, ex.getAndResetBuffer())
-			return
+			output := ex.getAndResetBuffer()
+			err := result.record(output, doneCallback(ctx, output))
+			return finish(err, ex.readErr)
+		}
+
+		if abort {
+			// Stop reading and drop the remaining content
+			ex.closeInput(flushErr)
+			return finish(nil, nil)
 		}
 	}
 }
 
+// shouldFlush reports whether the buffer has to be flushed right away
+// according to the policy
+func (ex *Exec) shouldFlush(first bool) bool {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+
+	if ex.lines == 0 {
+		// Already flushed by another event
+		return false
+	}
+
+	p := ex.policy
+	return (p.FirstLine && first) ||
+		(p.MaxBytes > 0 && ex.buffer.Len() >= p.MaxBytes) ||
+		(p.MaxLines > 0 && ex.lines >= p.MaxLines)
+}
+
 // appendLine adds a line to the buffer (thread-safe)
 func (ex *Exec) appendLine(line []byte) {
 	ex.mu.Lock()
 	defer ex.mu.Unlock()
 
+	if ex.silence > 0 {
+		ex.lastLine = string(line)
+	}
+
+	if ex.dedupe != DedupeOff {
+		key := ex.dedupeKey(line)
+		if ex.lines > 0 && key == ex.lastKey {
+			ex.repeats++
+			return
+		}
+		ex.closeRepeats()
+		ex.lastKey = key
+	}
+
 	ex.buffer.Write(line)
 	ex.buffer.WriteByte('\n')
+	ex.lines++
+
+	// Wake up processEvents without blocking the reader
+	select {
+	case ex.appended <- struct{}{}:
+	default:
+	}
+}
+
+// dedupeKey returns the key by which line is compared to the previous line.
+func (ex *Exec) dedupeKey(line []byte) string {
+	if ex.dedupe != DedupeNormalized {
+		return string(line)
+	}
+
+	// Replace each run of digits with a single '#'
+	key := make([]byte, 0, len(line))
+	for i, b := range line {
+		if '0' <= b && b <= '9' {
+			if i == 0 || line[i-1] < '0' || '9' < line[i-1] {
+				key = append(key, '#')
+			}
+			continue
+		}
+		key = append(key, b)
+	}
+	return string(key)
+}
+
+// closeRepeats appends the repeat counter to the last line in buffer if it
+// was repeated. ex.mu must be held.
+func (ex *Exec) closeRepeats() {
+	if ex.repeats == 0 {
+		return
+	}
+	// Replace the newline of the last line
+	ex.buffer.Truncate(ex.buffer.Len() - 1)
+	fmt.Fprintf(ex.buffer, " (×%d)\n", ex.repeats+1)
+	ex.repeats = 0
+}
+
+// getLastLine returns the last line buffered (thread-safe)
+func (ex *Exec) getLastLine() string {
+	ex.mu.Lock()
+	defer ex.mu.Unlock()
+	return ex.lastLine
 }
 
 // getAndResetBuffer returns the buffer content and clears it (thread-safe)
@@ -121,11 +544,41 @@ func (ex *Exec) getAndResetBuffer() string {
 	ex.mu.Lock()
 	defer ex.mu.Unlock()
 
+	ex.closeRepeats()
+
 	content := ex.buffer.String()
 	ex.buffer.Reset()
+	ex.lines = 0
 	return content
 }
 
+// policyTimer is a timer whose channel is nil while it is not running, so
+// that it can be used in select statements unconditionally
+type policyTimer struct {
+	timer *time.Timer
+	c     <-chan time.Time
+}
+
+func (t *policyTimer) reset(d time.Duration) {
+	if t.timer == nil {
+		t.timer = time.NewTimer(d)
+	} else {
+		t.timer.Reset(d)
+	}
+	t.c = t.timer.C
+}
+
+func (t *policyTimer) stop() {
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	t.c = nil
+}
+
+func (t *policyTimer) armed() bool {
+	return t.c != nil
+}
+
 // closeInput closes the underlying reader to unblock any blocked read operation
 func (ex *Exec) closeInput(err error) {
 	// Try pipe-specific close first (if applicable)
@@ -3,7 +3,10 @@ package throttle
 import (
 	"bytes"
 	"context"
+	"errors"
 	"io"
+	"slices"
+	"strings"
 	"testing"
 	"testing/synctest"
 	"time"
@@ -173,3 +176,460 @@ func TestRun_contextDone(t *testing.T) {
 		}
 	})
 }
+
+// startWithPolicy starts ex with policy and no interval ticks. It returns a
+// channel receiving the output of every flush and a function to finish the
+// input and wait for Start to return.
+func startWithPolicy(t *testing.T, ex *Exec, pw *io.PipeWriter, policy FlushPolicy) (<-chan string, func()) {
+	t.Helper()
+
+	ex.SetFlushPolicy(policy)
+
+	flushed := make(chan string, 100)
+	flushCallback := func(ctx context.Context, s string) error {
+		flushed <- s
+		return nil
+	}
+	doneCallback := func(ctx context.Context, s string) error {
+		return nil
+	}
+
+	exitC := make(chan struct{})
+	go func() {
+		ex.Start(t.Context(), nil, flushCallback, doneCallback)
+		close(exitC)
+	}()
+
+	return flushed, func() {
+		pw.Close()
+		<-exitC
+	}
+}
+
+func receiveFlushes(flushed <-chan string) []string {
+	var got []string
+	for {
+		select {
+		case s := <-flushed:
+			got = append(got, s)
+		default:
+			return got
+		}
+	}
+}
+
+func TestRun_flushPolicySize(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		pr, pw := io.Pipe()
+		ex := NewExec(pr)
+		flushed, finish := startWithPolicy(t, ex, pw, FlushPolicy{MaxLines: 2, MaxBytes: 10})
+		defer finish()
+
+		pw.Write([]byte("a\n"))
+		synctest.Wait()
+		if got := receiveFlushes(flushed); len(got) != 0 {
+			t.Fatalf("will not be flushed before reaching the limit %q", got)
+		}
+
+		pw.Write([]byte("b\n"))
+		synctest.Wait()
+		if got := receiveFlushes(flushed); len(got) != 1 || got[0] != "a\nb\n" {
+			t.Fatalf("flushed %q; want %q", got, "a\nb\n")
+		}
+
+		pw.Write([]byte("0123456789\n"))
+		synctest.Wait()
+		if got := receiveFlushes(flushed); len(got) != 1 || got[0] != "0123456789\n" {
+			t.Fatalf("flushed %q; want %q", got, "0123456789\n")
+		}
+	})
+}
+
+func TestRun_flushPolicyQuiet(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		pr, pw := io.Pipe()
+		ex := NewExec(pr)
+		flushed, finish := startWithPolicy(t, ex, pw, FlushPolicy{Quiet: 100 * time.Millisecond, MaxDelay: 975 * time.Millisecond})
+		defer finish()
+
+		start := time.Now()
+		// A line every 50ms never lets the input become quiet, so only
+		// MaxDelay flushes it
+		for range 30 {
+			pw.Write([]byte("a\n"))
+			time.Sleep(50 * time.Millisecond)
+		}
+		got := receiveFlushes(flushed)
+		if len(got) != 1 {
+			t.Fatalf("flushed %d times in %s; want once", len(got), time.Since(start))
+		}
+		if want := strings.Repeat("a\n", 20); got[0] != want {
+			t.Fatalf("flushed %q; want %q", got[0], want)
+		}
+
+		// 50ms has passed since the last line
+		time.Sleep(49 * time.Millisecond)
+		if got := receiveFlushes(flushed); len(got) != 0 {
+			t.Fatalf("will not be flushed before becoming quiet %q", got)
+		}
+
+		time.Sleep(time.Millisecond)
+		synctest.Wait()
+		if got := receiveFlushes(flushed); len(got) != 1 || got[0] != strings.Repeat("a\n", 10) {
+			t.Fatalf("flushed %q; want %q", got, strings.Repeat("a\n", 10))
+		}
+	})
+}
+
+func TestRun_flushPolicyFirstLine(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		pr, pw := io.Pipe()
+		ex := NewExec(pr)
+		flushed, finish := startWithPolicy(t, ex, pw, FlushPolicy{FirstLine: true})
+		defer finish()
+
+		pw.Write([]byte("first\n"))
+		synctest.Wait()
+		if got := receiveFlushes(flushed); len(got) != 1 || got[0] != "first\n" {
+			t.Fatalf("flushed %q; want %q", got, "first\n")
+		}
+
+		pw.Write([]byte("second\n"))
+		synctest.Wait()
+		if got := receiveFlushes(flushed); len(got) != 0 {
+			t.Fatalf("only the first line is flushed immediately %q", got)
+		}
+	})
+}
+
+func TestRun_flushError(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		for _, policy := range []ErrorPolicy{ContinueOnError, AbortOnError} {
+			pr, pw := io.Pipe()
+			ex := NewExec(pr)
+			ex.SetErrorPolicy(policy)
+
+			testC := make(chan time.Time)
+			failing := true
+			flushCallback := func(ctx context.Context, s string) error {
+				if failing {
+					return errors.New("post failed")
+				}
+				return nil
+			}
+
+			var doneOutput string
+			doneCallback := func(ctx context.Context, s string) error {
+				doneOutput = s
+				return nil
+			}
+
+			type startResult struct {
+				result Result
+				err    error
+			}
+			exitC := make(chan startResult)
+			go func() {
+				result, err := ex.Start(t.Context(), testC, flushCallback, doneCallback)
+				exitC <- startResult{result, err}
+			}()
+
+			pw.Write([]byte("abc\n"))
+			synctest.Wait()
+			testC <- time.Time{}
+			synctest.Wait()
+
+			if policy == ContinueOnError {
+				failing = false
+				pw.Write([]byte("defg\n"))
+				synctest.Wait()
+				testC <- time.Time{}
+				synctest.Wait()
+				pw.Write([]byte("hi\n"))
+				pw.Close()
+			}
+
+			r := <-exitC
+			if r.err == nil || !strings.Contains(r.err.Error(), "post failed") {
+				t.Fatalf("error = %v; want it to contain %q", r.err, "post failed")
+			}
+
+			switch policy {
+			case ContinueOnError:
+				expected := Result{Flushes: 3, FailedFlushes: 1, BytesPosted: 8}
+				if r.result != expected {
+					t.Errorf("result = %+v; want %+v", r.result, expected)
+				}
+				if doneOutput != "hi\n" {
+					t.Errorf("doneCallback got %q; want %q", doneOutput, "hi\n")
+				}
+			case AbortOnError:
+				expected := Result{Flushes: 1, FailedFlushes: 1}
+				if r.result != expected {
+					t.Errorf("result = %+v; want %+v", r.result, expected)
+				}
+				if _, err := pw.Write([]byte("jkl\n")); err == nil {
+					t.Error("the input will be closed on abort")
+				}
+			}
+		}
+	})
+}
+
+type errReader struct{}
+
+func (errReader) Read(p []byte) (int, error) {
+	return 0, errors.New("broken input")
+}
+
+func TestRun_readError(t *testing.T) {
+	ex := NewExec(io.MultiReader(strings.NewReader("abc\n"), errReader{}))
+
+	var doneOutput string
+	doneCallback := func(ctx context.Context, s string) error {
+		doneOutput = s
+		return nil
+	}
+	flushCallback := func(ctx context.Context, s string) error {
+		return nil
+	}
+
+	result, err := ex.Start(t.Context(), nil, flushCallback, doneCallback)
+	if err == nil || !strings.Contains(err.Error(), "broken input") {
+		t.Fatalf("error = %v; want it to contain %q", err, "broken input")
+	}
+	if doneOutput != "abc\n" {
+		t.Errorf("doneCallback got %q; want %q", doneOutput, "abc\n")
+	}
+	if result.Flushes != 1 || result.BytesPosted != 4 {
+		t.Errorf("result = %+v; want 1 flush of 4 bytes", result)
+	}
+}
+
+func TestRun_longLine(t *testing.T) {
+	long := strings.Repeat("a", 10000)
+
+	tests := []struct {
+		name         string
+		maxLineBytes int
+		input        string
+		want         string
+	}{
+		{
+			name:         "reassembled",
+			maxLineBytes: 0,
+			input:        long + "\nshort\n",
+			want:         long + "\nshort\n",
+		},
+		{
+			name:         "truncated",
+			maxLineBytes: 5000,
+			input:        long + "\nshort\n",
+			want:         strings.Repeat("a", 5000) + "…[truncated 5000 bytes]\nshort\n",
+		},
+		{
+			name:         "within the limit",
+			maxLineBytes: 5000,
+			input:        "abc\n\ndef",
+			want:         "abc\n\ndef\n",
+		},
+		{
+			name:         "multi-byte character",
+			maxLineBytes: 5,
+			input:        "aaaあいう\n",
+			want:         "aaa…[truncated 9 bytes]\n",
+		},
+		{
+			// The character is cut at the end of a read, and the next read
+			// must not fill the byte left
+			name:         "multi-byte character between reads",
+			maxLineBytes: 4095,
+			input:        strings.Repeat("a", 4094) + "é" + strings.Repeat("b", 5000) + "\n",
+			want:         strings.Repeat("a", 4094) + "…[truncated 5002 bytes]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ex := NewExec(strings.NewReader(tt.input))
+			ex.SetMaxLineBytes(tt.maxLineBytes)
+
+			var output string
+			callback := func(ctx context.Context, s string) error {
+				output += s
+				return nil
+			}
+
+			if _, err := ex.Start(t.Context(), nil, callback, callback); err != nil {
+				t.Fatal(err)
+			}
+			if output != tt.want {
+				t.Errorf("got %q; want %q", output, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_stages(t *testing.T) {
+	ex := NewExec(strings.NewReader("a\nb\nc\n"))
+	// Drop "b" and double the others, then uppercase them
+	ex.AddStage(func(line []byte, emit func([]byte)) {
+		if string(line) == "b" {
+			return
+		}
+		emit(line)
+		emit(line)
+	})
+	ex.AddStage(func(line []byte, emit func([]byte)) {
+		emit(bytes.ToUpper(line))
+	})
+
+	var output string
+	callback := func(ctx context.Context, s string) error {
+		output += s
+		return nil
+	}
+
+	if _, err := ex.Start(t.Context(), nil, callback, callback); err != nil {
+		t.Fatal(err)
+	}
+	if want := "A\nA\nC\nC\n"; output != want {
+		t.Errorf("got %q; want %q", output, want)
+	}
+}
+
+func TestRun_dedupe(t *testing.T) {
+	input := "start\nretrying\nretrying\nretrying\n12:00:01 waiting 1s\n12:00:02 waiting 2s\n12:00:03 waiting 3s\ndone\ndone\n"
+
+	tests := []struct {
+		name string
+		mode DedupeMode
+		want string
+	}{
+		{
+			name: "off",
+			mode: DedupeOff,
+			want: input,
+		},
+		{
+			name: "exact",
+			mode: DedupeExact,
+			want: "start\nretrying (×3)\n12:00:01 waiting 1s\n12:00:02 waiting 2s\n12:00:03 waiting 3s\ndone (×2)\n",
+		},
+		{
+			name: "normalized",
+			mode: DedupeNormalized,
+			want: "start\nretrying (×3)\n12:00:01 waiting 1s (×3)\ndone (×2)\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ex := NewExec(strings.NewReader(input))
+			ex.SetDedupe(tt.mode)
+
+			var output string
+			callback := func(ctx context.Context, s string) error {
+				output += s
+				return nil
+			}
+
+			if _, err := ex.Start(t.Context(), nil, callback, callback); err != nil {
+				t.Fatal(err)
+			}
+			if output != tt.want {
+				t.Errorf("got %q; want %q", output, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_dedupeWithinFlush(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		pr, pw := io.Pipe()
+		ex := NewExec(pr)
+		ex.SetDedupe(DedupeExact)
+
+		flushed, wait := startWithPolicy(t, ex, pw, FlushPolicy{MaxLines: 2})
+
+		for range 3 {
+			io.WriteString(pw, "same\n")
+		}
+		io.WriteString(pw, "other\n")
+		synctest.Wait()
+		// The repeats of a line flushed already start a new line
+		io.WriteString(pw, "other\n")
+		io.WriteString(pw, "other\n")
+		io.WriteString(pw, "last\n")
+		synctest.Wait()
+		wait()
+
+		got := strings.Join(receiveFlushes(flushed), "|")
+		if want := "same (×3)\nother\n|other (×2)\nlast\n"; got != want {
+			t.Errorf("got %q; want %q", got, want)
+		}
+	})
+}
+
+func TestRun_silenceAlert(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		pr, pw := io.Pipe()
+		ex := NewExec(pr)
+
+		var events []SilenceEvent
+		ex.SetSilenceAlert(10*time.Minute, func(ctx context.Context, ev SilenceEvent) {
+			events = append(events, ev)
+		})
+
+		flushed, wait := startWithPolicy(t, ex, pw, FlushPolicy{})
+
+		io.WriteString(pw, "started\n")
+		io.WriteString(pw, "working\n")
+		synctest.Wait()
+
+		time.Sleep(10*time.Minute + time.Second)
+		synctest.Wait()
+
+		// The output read before the silence is flushed first
+		if got := strings.Join(receiveFlushes(flushed), "|"); got != "started\nworking\n" {
+			t.Errorf("got %q; want %q", got, "started\nworking\n")
+		}
+
+		// Only reported once
+		time.Sleep(20 * time.Minute)
+		io.WriteString(pw, "done\n")
+		synctest.Wait()
+		wait()
+
+		expected := []SilenceEvent{
+			{LastLine: "working", Elapsed: 10 * time.Minute},
+			{Resumed: true, LastLine: "done", Elapsed: 30*time.Minute + time.Second},
+		}
+		if !slices.Equal(events, expected) {
+			t.Errorf("got %+v; want %+v", events, expected)
+		}
+	})
+}
+
+func TestRun_silenceAlertFromStart(t *testing.T) {
+	synctest.Test(t, func(t *testing.T) {
+		pr, pw := io.Pipe()
+		ex := NewExec(pr)
+
+		var events []SilenceEvent
+		ex.SetSilenceAlert(time.Minute, func(ctx context.Context, ev SilenceEvent) {
+			events = append(events, ev)
+		})
+
+		_, wait := startWithPolicy(t, ex, pw, FlushPolicy{})
+
+		time.Sleep(time.Minute)
+		synctest.Wait()
+		wait()
+
+		expected := []SilenceEvent{{Elapsed: time.Minute}}
+		if !slices.Equal(events, expected) {
+			t.Errorf("got %+v; want %+v", events, expected)
+		}
+	})
+}